@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	ir "github.com/smithjacobj/git-split/difftree"
+)
+
+// commandFunc runs a command typed after ":" with the rest of its line, and returns a message to
+// show the user.
+type commandFunc func(v *MainView, args string) (string, error)
+
+var g_Commands = map[string]commandFunc{
+	"select":   setSelectionMatching(ir.Selected),
+	"deselect": setSelectionMatching(ir.Deselected),
+	"revise":   reviseCommand(false),
+	"merge":    reviseCommand(true),
+}
+
+// startCommand opens a prompt for a command.
+func startCommand(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		return OpenPromptView(g, ":", "", nil, func(input string, ok bool) error {
+			input = strings.TrimSpace(input)
+			if !ok || len(input) == 0 {
+				return nil
+			}
+
+			name, args, _ := strings.Cut(input, " ")
+			command, ok := g_Commands[name]
+			if !ok {
+				return ShowMessage(g, fmt.Sprintf("Unknown command: %s", name))
+			}
+			message, err := command(v, strings.TrimSpace(args))
+			var revise *ReviseRequest
+			if errors.As(err, &revise) {
+				// going back to a part ends the main loop.
+				return err
+			} else if err != nil {
+				message = err.Error()
+			}
+			return ShowMessage(g, message)
+		})
+	}
+}
+
+// setSelectionMatching returns a command that sets the selection of the lines matching a pattern,
+// given as "/pattern/ [adds|deletes]". An empty pattern repeats the last search.
+func setSelectionMatching(state ir.SelectionState) commandFunc {
+	return func(v *MainView, args string) (string, error) {
+		end := strings.LastIndex(args, "/")
+		if !strings.HasPrefix(args, "/") || end == 0 {
+			return "", fmt.Errorf("usage: /pattern/ [adds|deletes]")
+		}
+
+		var ops []gitdiff.LineOp
+		switch strings.TrimSpace(args[end+1:]) {
+		case "":
+		case "adds":
+			ops = append(ops, gitdiff.OpAdd)
+		case "deletes":
+			ops = append(ops, gitdiff.OpDelete)
+		default:
+			return "", fmt.Errorf("usage: /pattern/ [adds|deletes]")
+		}
+
+		re := v.search
+		if pattern := args[1:end]; len(pattern) > 0 {
+			re = compileSearch(pattern)
+		} else if re == nil {
+			return "", fmt.Errorf("no previous search")
+		}
+
+		var count int
+		v.changeSelection(func() {
+			count = v.commit.SetSelectionMatching(re, state, ops...)
+		})
+		v.printContent()
+		if state == ir.Selected {
+			return fmt.Sprintf("Selected %d lines", count), nil
+		}
+		return fmt.Sprintf("Deselected %d lines", count), nil
+	}
+}
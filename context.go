@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	ir "github.com/smithjacobj/git-split/difftree"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+// k_MoreContextLines is how many lines showMoreContext adds on each side of a chunk.
+const k_MoreContextLines = 5
+
+// showMoreContext shows more lines around the chunk under the cursor, or around every chunk of the
+// file under it, from the file at HEAD, expanding them to show the lines. They are only displayed,
+// and can't be selected.
+func showMoreContext(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		x, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+
+		var file *ir.File
+		var chunks []*ir.Chunk
+		if chunk := chunkOf(node); chunk != nil {
+			file, chunks = chunk.Parent, []*ir.Chunk{chunk}
+		} else {
+			file = node.(*ir.File)
+			chunks = file.Chunks
+		}
+		if file.IsNew || len(chunks) == 0 {
+			return ShowMessage(g, "No context to show")
+		}
+
+		oldFile, err := headFileLines(file.OldName)
+		if err != nil {
+			return ShowMessage(g, fmt.Sprintf("Can't read %s at HEAD: %s", file.OldName, err))
+		}
+		added := false
+		for _, chunk := range chunks {
+			added = chunk.ShowMoreContext(oldFile, k_MoreContextLines) || added
+			chunk.Expanded = ir.Expanded
+		}
+		file.Expanded = ir.Expanded
+		if !added {
+			return ShowMessage(g, "No more context to show")
+		}
+
+		v.printContent()
+		v.View.SetCursor(x, v.lineNumberOf(node))
+		fixScroll(v.View)
+		return nil
+	}
+}
+
+// headFileLines returns the lines of the file at HEAD, with their ends of line.
+func headFileLines(path string) ([]string, error) {
+	cmd := git.GitCmd("show", "HEAD:"+path)
+	// the output isn't trimmed, as leading and trailing blank lines are part of the file.
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, cmd.String())
+	}
+	lines := strings.SplitAfter(string(output), "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/fatih/color"
+)
+
+const k_ContinueView = "continue"
+
+var ErrFinish = fmt.Errorf("commit the remaining changes and quit")
+
+// k_ContinueChoices are the choices of the continue view, with the key that makes each one.
+var k_ContinueChoices = []struct {
+	key         rune
+	description string
+	// err is returned from the main loop for the choice, or nil to carry on splitting.
+	err error
+}{
+	{'c', "continue splitting", nil},
+	{'f', "finish: commit the remaining changes as the last part", ErrFinish},
+	{'a', "abort: restore the original branch", gocui.ErrQuit},
+}
+
+// ContinueView asks what to do after a part is committed, over the main view showing the remaining
+// changes. A choice is made with its key, or by moving to it and pressing enter; escape continues.
+type ContinueView struct {
+	*gocui.Gui
+	*gocui.View
+
+	previousView string
+}
+
+// OpenContinueView opens the continue view for the part that was committed, and makes it the
+// current view until a choice is made.
+func OpenContinueView(g *gocui.Gui, part int) error {
+	v := &ContinueView{Gui: g}
+	if current := g.CurrentView(); current != nil {
+		v.previousView = current.Name()
+	}
+
+	var err error
+	x0, y0, x1, y1 := continueViewBounds(g)
+	if v.View, err = g.SetView(k_ContinueView, x0, y0, x1, y1, 0); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	v.View.Title = fmt.Sprintf("Part %d committed", part)
+	v.View.Highlight = true
+	v.View.SelBgColor = gocui.ColorWhite
+	v.View.SelFgColor = gocui.ColorBlack
+	v.View.Clear()
+	fmt.Fprintln(v.View, " Some changes remain. What next?")
+	fmt.Fprintln(v.View)
+	for _, choice := range k_ContinueChoices {
+		fmt.Fprintf(v.View, " %s %s\n", color.CyanString("[%c]", choice.key), choice.description)
+	}
+	v.View.SetCursor(0, k_ContinueFirstChoiceRow)
+
+	if err := v.setKeybindings(); err != nil {
+		return err
+	}
+	_, err = g.SetCurrentView(k_ContinueView)
+	return err
+}
+
+// k_ContinueFirstChoiceRow is the row of the first choice, after the question.
+const k_ContinueFirstChoiceRow = 2
+
+// LayoutContinueView keeps the continue view in the middle of the screen, if it is shown.
+func LayoutContinueView(g *gocui.Gui) error {
+	if _, err := g.View(k_ContinueView); err != nil {
+		return nil
+	}
+	x0, y0, x1, y1 := continueViewBounds(g)
+	_, err := g.SetView(k_ContinueView, x0, y0, x1, y1, 0)
+	return err
+}
+
+func continueViewBounds(g *gocui.Gui) (x0, y0, x1, y1 int) {
+	width, height := 62, k_ContinueFirstChoiceRow+len(k_ContinueChoices)+1
+	maxX, maxY := g.Size()
+	x0, y0 = (maxX-width)/2, (maxY-height)/2
+	return x0, y0, x0 + width, y0 + height
+}
+
+// k_ContinueKeys are the keys of the continue view besides those of the choices, which are listed
+// with all keys.
+var k_ContinueKeys = []struct {
+	keys    []string
+	usage   string
+	handler func(v *ContinueView) func(*gocui.Gui, *gocui.View) error
+}{
+	{[]string{"up"}, "move up", func(v *ContinueView) func(*gocui.Gui, *gocui.View) error { return v.moveCursor(-1) }},
+	{[]string{"down"}, "move down", func(v *ContinueView) func(*gocui.Gui, *gocui.View) error { return v.moveCursor(1) }},
+	{[]string{"enter"}, "make the choice under the cursor", func(v *ContinueView) func(*gocui.Gui, *gocui.View) error { return v.chooseAtCursor }},
+	{[]string{"esc"}, "continue splitting", func(v *ContinueView) func(*gocui.Gui, *gocui.View) error { return v.choose(nil) }},
+}
+
+func (v *ContinueView) setKeybindings() error {
+	for _, choice := range k_ContinueChoices {
+		if err := v.Gui.SetKeybinding(k_ContinueView, choice.key, gocui.ModNone, v.choose(choice.err)); err != nil {
+			return err
+		}
+	}
+	for _, b := range k_ContinueKeys {
+		for _, name := range b.keys {
+			key, mod, err := parseKey(name)
+			if err != nil {
+				return err
+			}
+			if err := v.Gui.SetKeybinding(k_ContinueView, key, mod, b.handler(v)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *ContinueView) moveCursor(dy int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		_, y := v.View.Cursor()
+		if y += dy; y >= k_ContinueFirstChoiceRow && y < k_ContinueFirstChoiceRow+len(k_ContinueChoices) {
+			v.View.SetCursor(0, y)
+		}
+		return nil
+	}
+}
+
+func (v *ContinueView) chooseAtCursor(g *gocui.Gui, view *gocui.View) error {
+	_, y := v.View.Cursor()
+	return v.choose(k_ContinueChoices[y-k_ContinueFirstChoiceRow].err)(g, view)
+}
+
+// choose closes the view and returns err, which ends the main loop unless it is nil.
+func (v *ContinueView) choose(err error) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		g.DeleteKeybindings(k_ContinueView)
+		if deleteErr := g.DeleteView(k_ContinueView); deleteErr != nil {
+			return deleteErr
+		}
+		if len(v.previousView) > 0 {
+			if _, setErr := g.SetCurrentView(v.previousView); setErr != nil {
+				return setErr
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	ir "github.com/smithjacobj/git-split/difftree"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+// k_DiffAlgorithms are the algorithms cycled through from the UI, where "" is Git's default.
+var k_DiffAlgorithms = []string{"", "patience", "histogram"}
+
+// k_DefaultDiffOptions generate the diff the way Git does by default.
+var k_DefaultDiffOptions = DiffOptions{Context: 3}
+
+// g_DiffOptions are the options the diff being split is generated with, which are kept for the
+// following parts.
+var g_DiffOptions = k_DefaultDiffOptions
+
+// DiffOptions change how the diff being split is generated, which can give cleaner chunks to split.
+type DiffOptions struct {
+	// Algorithm is the diff algorithm, or "" for Git's default.
+	Algorithm string
+	// Context is the number of context lines around changes, which is at least 1, as a patch
+	// without context would need to be applied differently.
+	Context int
+	// IgnoreWhitespace leaves out changes that are only whitespace. They are left for a later part,
+	// as they are still different from the original commit.
+	IgnoreWhitespace bool
+}
+
+func (o DiffOptions) args() []string {
+	args := []string{fmt.Sprintf("-U%d", o.Context)}
+	if len(o.Algorithm) > 0 {
+		args = append(args, "--diff-algorithm="+o.Algorithm)
+	}
+	if o.IgnoreWhitespace {
+		args = append(args, "--ignore-all-space")
+	}
+	return args
+}
+
+// String describes the options like their git diff arguments.
+func (o DiffOptions) String() string {
+	return strings.Join(o.args(), " ")
+}
+
+// diff returns the patch between the refs, generated with the options.
+func diff(options DiffOptions, ref1, ref2 string) (buf *bytes.Buffer, err error) {
+	buf = &bytes.Buffer{}
+	cmd := git.GitCmd(append([]string{"diff", ref1, ref2, "-p", "--no-color"}, options.args()...)...)
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+
+	err = cmd.Run()
+	return
+}
+
+// parseDiff parses the diff between HEAD and the target commit, generated with the options.
+func parseDiff(options DiffOptions) (*ir.Commit, error) {
+	return parseDiffBetween(options, "HEAD", g_TargetRef)
+}
+
+// parseDiffBetween parses the diff between the refs, generated with the options.
+func parseDiffBetween(options DiffOptions, ref1, ref2 string) (*ir.Commit, error) {
+	patch, err := diff(options, ref1, ref2)
+	if err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, patch)
+	}
+	return ir.ParseCommit(patch)
+}
+
+// applyPatch applies a patch of a diff generated with the options. The context lines of a diff that
+// ignores whitespace are from the new file, so their whitespace mustn't need to match.
+func applyPatch(options DiffOptions, r io.Reader) error {
+	args := []string{"apply"}
+	if options.IgnoreWhitespace {
+		args = append(args, "--ignore-whitespace")
+	}
+	cmd := git.GitCmd(append(args, "-")...)
+	cmd.Stdin = r
+	_, err := cmd.FormatOutput(cmd.CombinedOutput())
+	return err
+}
+
+// g_RemainderCache is the result of remainderEmpty for the last patch it checked, as the status is
+// shown again after every key.
+var g_RemainderCache struct {
+	patch string
+	empty bool
+}
+
+// remainderEmpty returns whether committing the selection of the commit, whose diff was generated
+// with the options, would leave nothing to split. Once every file is selected, edited chunks and
+// changes left out by the options can still remain, so the selection is then applied to a temporary
+// index and compared with the target commit. It returns false if that fails.
+func remainderEmpty(c *ir.Commit, options DiffOptions) bool {
+	if c.Stats().RemainingFiles > 0 {
+		return false
+	} else if !c.HasEditedChunks() && options == k_DefaultDiffOptions {
+		return true
+	}
+
+	patch := c.AsPatchString()
+	if patch != g_RemainderCache.patch {
+		empty, err := applyToTemporaryIndex(options, patch)
+		g_RemainderCache.patch, g_RemainderCache.empty = patch, err == nil && empty
+	}
+	return g_RemainderCache.empty
+}
+
+// applyToTemporaryIndex applies the patch, generated with the options, to HEAD in a temporary index,
+// and returns whether the result is the same as the target commit.
+func applyToTemporaryIndex(options DiffOptions, patch string) (bool, error) {
+	dir, err := os.MkdirTemp("", "git-split")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+	env := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(dir, "index"))
+
+	apply := []string{"apply", "--cached"}
+	if options.IgnoreWhitespace {
+		apply = append(apply, "--ignore-whitespace")
+	}
+	var tree string
+	for _, args := range [][]string{{"read-tree", "HEAD"}, append(apply, "-"), {"write-tree"}} {
+		cmd := git.GitCmd(args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(patch)
+		if tree, err = cmd.FormatOutput(cmd.CombinedOutput()); err != nil {
+			return false, err
+		}
+	}
+	isDifferent, err := git.IsDifferent(tree, g_TargetRef)
+	return !isDifferent, err
+}
+
+// rediff generates the diff again with the options changed by change, carrying over the selection
+// where lines are changed in both diffs, and the cursor to the same file. Undo history is cleared,
+// as it refers to the lines of the old diff.
+func rediff(v *MainView, change func(o *DiffOptions)) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		if v.commit.HasEditedChunks() {
+			return ShowMessage(g, "Can't change the diff, as it would undo edited chunks")
+		}
+
+		options := g_DiffOptions
+		change(&options)
+		if options == g_DiffOptions {
+			return ShowMessage(g, fmt.Sprintf("Already using git diff %s", options))
+		}
+		commit, err := parseDiff(options)
+		if err != nil {
+			// the error has Git's output on the lines after it, which are kept on the message's line.
+			message := strings.ReplaceAll(strings.TrimSpace(err.Error()), "\n", " ")
+			return ShowMessage(g, fmt.Sprintf("Can't generate the diff with git diff %s: %s", options, message))
+		} else if len(commit.Files) == 0 {
+			return ShowMessage(g, fmt.Sprintf("No changes with git diff %s", options))
+		}
+
+		_, y := v.View.Cursor()
+		var file *ir.File
+		if chunk := chunkOf(v.commit.LineMap[y]); chunk != nil {
+			file = chunk.Parent
+		} else {
+			file = v.commit.LineMap[y].(*ir.File)
+		}
+
+		g_DiffOptions = options
+		v.commit.Rediff(commit)
+		v.visualAnchor = nil
+		v.undoStack, v.redoStack = nil, nil
+		v.printContent()
+
+		y = 0
+		for _, f := range v.commit.Files {
+			if f.OldName == file.OldName && f.NewName == file.NewName && !f.Hidden {
+				y = f.LineNumber
+			}
+		}
+		v.View.SetCursor(0, y)
+		fixScroll(v.View)
+		return ShowMessage(g, fmt.Sprintf("Diff generated with git diff %s", options))
+	}
+}
+
+func cycleDiffAlgorithm(o *DiffOptions) {
+	for i, algorithm := range k_DiffAlgorithms {
+		if algorithm == o.Algorithm {
+			o.Algorithm = k_DiffAlgorithms[(i+1)%len(k_DiffAlgorithms)]
+			return
+		}
+	}
+	o.Algorithm = k_DiffAlgorithms[0]
+}
+
+func changeDiffContext(dContext int) func(o *DiffOptions) {
+	return func(o *DiffOptions) {
+		if o.Context += dContext; o.Context < 1 {
+			o.Context = 1
+		}
+	}
+}
+
+func toggleIgnoreWhitespace(o *DiffOptions) {
+	o.IgnoreWhitespace = !o.IgnoreWhitespace
+}
@@ -0,0 +1,57 @@
+package difftree
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// ShowMoreContext shows up to n more lines of the old file before and after the chunk, where
+// oldFile has the lines of the old file with their ends of line. The lines are only displayed, as
+// context lines that aren't part of the diff, and stop at the ends of the file and at the context
+// shown for the neighbouring chunks. It returns whether any lines were added.
+func (c *Chunk) ShowMoreContext(oldFile []string, n int) bool {
+	// the range of lines that can be shown, as indices into oldFile.
+	first, last := 0, len(oldFile)
+	for i, chunk := range c.Parent.Chunks {
+		if chunk != c {
+			continue
+		}
+		if i > 0 {
+			previous := c.Parent.Chunks[i-1]
+			first = int(previous.oldEnd()-1) + len(previous.ContextAfter)
+		}
+		if i+1 < len(c.Parent.Chunks) {
+			next := c.Parent.Chunks[i+1]
+			last = int(next.oldStart()-1) - len(next.ContextBefore)
+		}
+	}
+
+	// the range of lines already shown.
+	start := int(c.oldStart()-1) - len(c.ContextBefore)
+	end := int(c.oldEnd()-1) + len(c.ContextAfter)
+	if start < first || end > last {
+		// the old file doesn't match the chunk, so we don't know where its lines are.
+		return false
+	}
+
+	newStart, newEnd := start-n, end+n
+	if newStart < first {
+		newStart = first
+	}
+	if newEnd > last {
+		newEnd = last
+	}
+
+	before := make([]*Line, 0, start-newStart+len(c.ContextBefore))
+	for _, text := range oldFile[newStart:start] {
+		before = append(before, c.contextLine(text))
+	}
+	c.ContextBefore = append(before, c.ContextBefore...)
+	for _, text := range oldFile[end:newEnd] {
+		c.ContextAfter = append(c.ContextAfter, c.contextLine(text))
+	}
+	return newStart < start || newEnd > end
+}
+
+func (c *Chunk) contextLine(text string) *Line {
+	return &Line{Line: gitdiff.Line{Op: gitdiff.OpContext, Line: text}, Parent: c, Extra: true}
+}
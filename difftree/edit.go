@@ -0,0 +1,137 @@
+package difftree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+const k_EditComment = "#"
+const k_EditInstructions = `# ---
+# To remove '-' lines, make them ' ' lines (context).
+# To remove '+' lines, delete them.
+# To change a line, edit its '+' line; new '+' lines may be added anywhere.
+# Lines starting with # will be removed.
+#
+# The ' ' and '-' lines must still match the original file, or the edit is rejected. The remaining
+# changes will be shown for the next commit as usual. To abort the edit, delete all lines.
+`
+
+var ErrEditAborted = fmt.Errorf("edit aborted")
+
+// AsEditString returns the chunk as it would be committed, in a format for editing with a text
+// editor and then passing to ApplyEdit.
+func (c *Chunk) AsEditString() string {
+	sb := &strings.Builder{}
+	fmt.Fprintln(sb, k_EditComment, "Manual chunk edit mode - see bottom for a quick guide.")
+	fmt.Fprintln(sb, c.Header())
+	for _, l := range c.Lines {
+		s := l.String()
+		if l.selection == Deselected {
+			if l.Op == gitdiff.OpAdd {
+				continue
+			} else if l.Op == gitdiff.OpDelete {
+				s = gitdiff.OpContext.String() + l.Line.Line
+			}
+		}
+
+		fmt.Fprint(sb, s)
+		if l.NoEOL() {
+			fmt.Fprint(sb, "\n", k_NoEOL)
+		}
+	}
+	fmt.Fprint(sb, k_EditInstructions)
+	return sb.String()
+}
+
+// ApplyEdit replaces the lines of the chunk with the lines of s, an edited version of the output of
+// AsEditString. All of the new lines are selected. It returns ErrEditAborted if s has no lines, or
+// an error describing the problem if s doesn't apply to the same lines of the old file.
+//
+// Nothing else needs to track the edit, as the next part of the split is diffed against the
+// original target, which puts back anything that the edit changed.
+func (c *Chunk) ApplyEdit(s string) error {
+	lines := make([]*Line, 0, len(c.Lines))
+	markers := map[*Line]int{}
+	for i, text := range strings.SplitAfter(s, "\n") {
+		if len(text) == 0 {
+			continue
+		} else if !strings.HasSuffix(text, "\n") {
+			// only the marker removes the end of line, not an editor that doesn't add one.
+			text += "\n"
+		}
+
+		switch {
+		case strings.HasPrefix(text, k_EditComment), strings.HasPrefix(text, "@@"):
+			continue
+		case text == "\n":
+			// editors that trim trailing whitespace leave blank context lines empty.
+			text = gitdiff.OpContext.String() + text
+		case text == k_NoEOL:
+			if len(lines) == 0 {
+				return fmt.Errorf("line %d: %q must follow another line", i+1, strings.TrimSpace(text))
+			}
+			previous := lines[len(lines)-1]
+			previous.Line.Line = strings.TrimSuffix(previous.Line.Line, "\n")
+			markers[previous] = i + 1
+			continue
+		}
+
+		var op gitdiff.LineOp
+		switch text[0] {
+		case ' ':
+			op = gitdiff.OpContext
+		case '-':
+			op = gitdiff.OpDelete
+		case '+':
+			op = gitdiff.OpAdd
+		default:
+			return fmt.Errorf("line %d: lines must start with ' ', '-' or '+'", i+1)
+		}
+		lines = append(lines, &Line{Line: gitdiff.Line{Op: op, Line: text[1:]}, selection: Selected})
+	}
+	if len(lines) == 0 {
+		return ErrEditAborted
+	}
+
+	// a file can only end without a newline at its last line, so no line of the same side may follow.
+	for j, l := range lines {
+		if !l.NoEOL() {
+			continue
+		}
+		for _, later := range lines[j+1:] {
+			if (l.Old() && later.Old()) || (l.New() && later.New()) {
+				return fmt.Errorf("line %d: %q must follow the last line of the old or new file", markers[l], strings.TrimSpace(k_NoEOL))
+			}
+		}
+	}
+
+	// the edit is applied to the old file, so that side mustn't change.
+	oldLines := make([]string, 0, len(c.Lines))
+	for _, l := range c.Lines {
+		if l.Old() {
+			oldLines = append(oldLines, l.Line.Line)
+		}
+	}
+	i := 0
+	for _, l := range lines {
+		if !l.Old() {
+			continue
+		} else if i >= len(oldLines) || l.Line.Line != oldLines[i] {
+			return fmt.Errorf("the ' ' and '-' lines don't match the original file at %q", strings.TrimSpace(l.Line.Line))
+		}
+		i++
+	}
+	if i != len(oldLines) {
+		return fmt.Errorf("the ' ' and '-' lines are missing %q and later lines of the original file", strings.TrimSpace(oldLines[i]))
+	}
+
+	newStart := c.NewPosition
+	if c.NewLines == 0 {
+		newStart++
+	}
+	c.setLines(lines, c.Comment, c.oldStart(), newStart)
+	c.Edited = true
+	return nil
+}
@@ -0,0 +1,102 @@
+package difftree
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseTestCommit parses the patch, failing the test if it can't.
+func parseTestCommit(t *testing.T, patch string) *Commit {
+	t.Helper()
+	commit, err := ParseCommit(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("can't parse the patch: %s", err)
+	}
+	return commit
+}
+
+const k_TestAddPatch = `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,2 +1,3 @@
+ a
+ b
++c
+`
+
+const k_TestNoEOLPatch = `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`
+
+func TestApplyEditNoEOL(t *testing.T) {
+	tests := []struct {
+		name, patch, edit string
+		wantErr           bool
+		wantPatchSuffix   string
+	}{
+		{"at the end of the new file", k_TestAddPatch, " a\n b\n+j\n+k\n\\ No newline at end of file\n", false, "+j\n+k\n\\ No newline at end of file\n"},
+		{"between added lines", k_TestAddPatch, " a\n b\n+j\n\\ No newline at end of file\n+k\n", true, ""},
+		{"after a context line followed by an added line", k_TestAddPatch, " a\n b\n\\ No newline at end of file\n+k\n", true, ""},
+		{"at the end of the old file, followed by added lines", k_TestNoEOLPatch, " a\n-b\n\\ No newline at end of file\n+b\n+c\n", false, "-b\n\\ No newline at end of file\n+b\n+c\n"},
+		{"before the last line of the old file", k_TestNoEOLPatch, " a\n\\ No newline at end of file\n-b\n\\ No newline at end of file\n+b\n", true, ""},
+	}
+	for _, test := range tests {
+		commit := parseTestCommit(t, test.patch)
+		chunk := commit.Files[0].Chunks[0]
+		err := chunk.ApplyEdit(test.edit)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: the edit was accepted", test.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if patch := commit.AsPatchString(); !strings.HasSuffix(patch, test.wantPatchSuffix) {
+			t.Errorf("%s: the patch ends with the wrong lines:\n%s", test.name, patch)
+		}
+	}
+}
+
+func TestSplitEditedChunk(t *testing.T) {
+	commit := parseTestCommit(t, `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,7 +1,7 @@
+-a
++A
+ b
+ c
+ d
+ e
+ f
+-g
++G
+`)
+	chunk := commit.Files[0].Chunks[0]
+	if err := chunk.ApplyEdit(chunk.AsEditString()); err != nil {
+		t.Fatal(err)
+	}
+	chunks := chunk.Split()
+	if len(chunks) != 2 {
+		t.Fatalf("split into %d chunks, not 2", len(chunks))
+	}
+	for i, c := range chunks {
+		if !c.Edited {
+			t.Errorf("chunk %d isn't edited", i+1)
+		}
+	}
+	if !commit.HasEditedChunks() {
+		t.Error("the commit has no edited chunks")
+	}
+}
@@ -0,0 +1,50 @@
+package difftree
+
+import (
+	"path"
+	"strings"
+)
+
+// SetFilter hides the files whose old and new names don't match the pattern, which is a glob if it
+// has any of "*?[", matching the whole path or just the base name, or a substring of the path
+// otherwise. Like searches, case is ignored unless the pattern has an upper case letter. An empty
+// pattern shows every file. Hidden files keep their selection, and are still committed. It returns
+// the number of hidden files.
+func (commit *Commit) SetFilter(pattern string) int {
+	commit.Filter = pattern
+	hidden := 0
+	for _, f := range commit.Files {
+		f.Hidden = len(pattern) > 0 && !matchesFilter(pattern, f.OldName) && !matchesFilter(pattern, f.NewName)
+		if f.Hidden {
+			hidden++
+		}
+	}
+	return hidden
+}
+
+// HiddenFileCount returns the number of files hidden by the filter.
+func (commit *Commit) HiddenFileCount() int {
+	hidden := 0
+	for _, f := range commit.Files {
+		if f.Hidden {
+			hidden++
+		}
+	}
+	return hidden
+}
+
+func matchesFilter(pattern, name string) bool {
+	if len(name) == 0 {
+		return false
+	} else if strings.ToLower(pattern) == pattern {
+		name = strings.ToLower(name)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.Contains(name, pattern)
+	} else if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, path.Base(name))
+	return ok
+}
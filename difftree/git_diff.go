@@ -7,6 +7,7 @@ import (
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
 	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
 )
 
 const k_MissingSpacer = "   "
@@ -74,6 +75,11 @@ type Commit struct {
 	LineMap []Selectable
 	// Description includes the commit details, like commit message, etc.
 	Description string
+	// Filter is the pattern set by SetFilter.
+	Filter string
+	// WrapWidth is the width in cells that String wraps the lines of chunks at, continuing them on
+	// rows that map to the same line, or 0 to not wrap them.
+	WrapWidth int
 }
 
 // FileFunc is a callback for ForEachNode. Return an error to break out of the loop.
@@ -104,9 +110,16 @@ func (c *Commit) ForEachNode(ffn FileFunc, cfn ChunkFunc, lfn LineFunc) error {
 }
 
 func (commit *Commit) String() string {
+	return commit.render(commit.writeLine)
+}
+
+// lineWriter writes the rows of a line of an expanded chunk and adds them to the line map.
+type lineWriter func(sb *strings.Builder, f *File, l *Line)
+
+func (commit *Commit) render(writeLine lineWriter) string {
 	sb := &strings.Builder{}
 	commit.LineMap = commit.LineMap[:0]
-	commit.ForEachNode(
+	commit.ForEachShownNode(
 		func(f *File) error {
 			f.LineNumber = len(commit.LineMap)
 			commit.LineMap = append(commit.LineMap, f)
@@ -136,7 +149,11 @@ func (commit *Commit) String() string {
 			commit.LineMap = append(commit.LineMap, c)
 
 			fmt.Fprint(sb, k_DisplayTab, c.Expanded.String())
-			fmt.Fprintf(sb, " %s %s\n", c.selection.String(), color.CyanString(c.Header()))
+			fmt.Fprintf(sb, " %s %s", c.selection.String(), color.CyanString(c.Header()))
+			if c.Edited {
+				fmt.Fprint(sb, color.YellowString("(edited)"))
+			}
+			fmt.Fprintln(sb)
 			return nil
 		},
 		func(f *File, c *Chunk, l *Line) error {
@@ -144,31 +161,16 @@ func (commit *Commit) String() string {
 				return ErrBreak
 			}
 
-			commit.LineMap = append(commit.LineMap, l)
-
-			fmt.Fprint(sb, k_DisplayTab, k_DisplayTab)
-			lineColor := color.FgWhite
-			if l.Op == gitdiff.OpAdd {
-				lineColor = color.FgGreen
-			} else if l.Op == gitdiff.OpDelete {
-				lineColor = color.FgRed
-			}
-			// aligns as there's no collapse/expand on lines
-			fmt.Fprint(sb, k_MissingSpacer, " ")
-			if l.Op == gitdiff.OpContext {
-				// selecting or deselecting context lines is pointless
-				fmt.Fprint(sb, k_MissingSpacer)
-			} else {
-				fmt.Fprint(sb, l.selection.String())
+			if l == c.Lines[0] {
+				for _, extra := range c.ContextBefore {
+					writeLine(sb, f, extra)
+				}
 			}
-
-			fmt.Fprintf(sb, " \u001b[%dm%s\u001b[%dm", lineColor, l.String(), color.FgWhite)
-
-			if l.NoEOL() {
-				// we make sure that the line map remains normalized even with this added virtual line.
-				commit.LineMap = append(commit.LineMap, l)
-				fmt.Fprint(sb, "\n", k_DisplayTab, k_DisplayTab, k_DisplayTab, k_DisplayTab)
-				fmt.Fprintf(sb, "\u001b[%dm%s%s\u001b[%dm", lineColor, l.Op.String(), k_NoEOL, color.FgWhite)
+			writeLine(sb, f, l)
+			if l == c.Lines[len(c.Lines)-1] {
+				for _, extra := range c.ContextAfter {
+					writeLine(sb, f, extra)
+				}
 			}
 			return nil
 		},
@@ -176,47 +178,150 @@ func (commit *Commit) String() string {
 	return sb.String()
 }
 
-func (c *Commit) AsPatchString() string {
-	sb := &strings.Builder{}
+// Column is a part of a displayed row that can be clicked.
+type Column int
+
+const (
+	OtherColumn Column = iota
+	ExpanderColumn
+	CheckboxColumn
+)
 
-	c.ForEachNode(
+// ColumnAt returns the part of a row displaying the node at the column x, in either layout.
+func ColumnAt(node Selectable, x int) Column {
+	// where the expander would be for each kind of node, as they are indented by a tab each.
+	expander := 0
+	switch node := node.(type) {
+	case *Chunk:
+		expander = len(k_DisplayTab)
+	case *Line:
+		if node.Op == gitdiff.OpContext {
+			return OtherColumn
+		}
+		expander = 2 * len(k_DisplayTab)
+	case *LinePair:
+		expander = 2 * len(k_DisplayTab)
+	}
+
+	checkbox := expander + len(k_MissingSpacer) + 1
+	switch {
+	case x >= expander && x < expander+len(k_MissingSpacer):
+		switch node.(type) {
+		case *File, *Chunk:
+			return ExpanderColumn
+		}
+	case x >= checkbox && x < checkbox+len(k_MissingSpacer):
+		return CheckboxColumn
+	}
+	return OtherColumn
+}
+
+// writeLine writes the line in the unified layout, under the chunk header.
+func (commit *Commit) writeLine(sb *strings.Builder, f *File, l *Line) {
+	commit.LineMap = append(commit.LineMap, l)
+
+	prefix := &strings.Builder{}
+	fmt.Fprint(prefix, k_DisplayTab, k_DisplayTab)
+	// aligns as there's no collapse/expand on lines, but joins up the lines of a pair.
+	if l.Pair == nil {
+		fmt.Fprint(prefix, k_MissingSpacer, " ")
+	} else if l == l.Pair.Delete {
+		fmt.Fprint(prefix, k_PairTop, " ")
+	} else {
+		fmt.Fprint(prefix, k_PairBottom, " ")
+	}
+	if l.Op == gitdiff.OpContext {
+		// selecting or deselecting context lines is pointless
+		fmt.Fprint(prefix, k_MissingSpacer)
+	} else {
+		fmt.Fprint(prefix, l.selection.String())
+	}
+	fmt.Fprint(prefix, " ")
+	sb.WriteString(prefix.String())
+
+	runes := l.displayRunes(f.language())
+	rows := [][]styledRune{runes}
+	prefixWidth := runewidth.StringWidth(prefix.String())
+	if commit.WrapWidth > 0 {
+		width := commit.WrapWidth - prefixWidth
+		if width < k_MinColumnWidth {
+			width = k_MinColumnWidth
+		}
+		rows = wrapRunes(runes, width)
+	}
+	for i, row := range rows {
+		if i > 0 {
+			// continuation rows are indented to line up with the start of the line.
+			commit.LineMap = append(commit.LineMap, l)
+			fmt.Fprint(sb, strings.Repeat(" ", prefixWidth))
+		}
+		writeStyledRunes(sb, row)
+		fmt.Fprintln(sb)
+	}
+
+	if l.NoEOL() {
+		// we make sure that the line map remains normalized even with this added virtual line.
+		commit.LineMap = append(commit.LineMap, l)
+		fmt.Fprint(sb, k_DisplayTab, k_DisplayTab, k_DisplayTab, k_DisplayTab)
+		marker := []styledRune{}
+		for _, r := range l.Op.String() + strings.TrimSuffix(k_NoEOL, "\n") {
+			marker = append(marker, l.styled(r))
+		}
+		writeStyledRunes(sb, marker)
+		fmt.Fprintln(sb)
+	}
+}
+
+// ForEachShownNode is like ForEachNode, but skips the files hidden by the filter.
+func (c *Commit) ForEachShownNode(ffn FileFunc, cfn ChunkFunc, lfn LineFunc) error {
+	return c.ForEachNode(
 		func(f *File) error {
-			if f.selection == Deselected {
+			if f.Hidden {
 				return ErrContinue
+			} else if ffn != nil {
+				return ffn(f)
 			}
-
-			fmt.Fprint(sb, f.Header())
 			return nil
 		},
-		func(_ *File, c *Chunk) error {
-			if c.selection == Deselected {
-				return ErrContinue
-			}
+		cfn,
+		lfn,
+	)
+}
 
-			// we will use the outdated chunk line counts and use git-apply --recount
-			fmt.Fprintln(sb, c.Header())
-			return nil
-		},
-		func(_ *File, _ *Chunk, l *Line) error {
-			s := l.String()
-
-			if l.selection == Deselected {
-				if l.Op == gitdiff.OpAdd {
-					return ErrContinue
-				} else if l.Op == gitdiff.OpDelete {
-					// removing OpDeletes makes the patch fail, so we change them into context lines
-					// for patches.
-					s = gitdiff.OpContext.String() + l.Line.Line
-				}
+func (c *Commit) AsPatchString() string {
+	sb := &strings.Builder{}
+
+	for _, file := range c.Files {
+		if file.selection == Deselected {
+			continue
+		}
+
+		fmt.Fprint(sb, file.Header())
+
+		// offset is the difference in line numbers between the old and new files caused by the
+		// hunks already written for this file.
+		var offset int64
+		for _, h := range file.hunks() {
+			if h.selection() == Deselected {
+				continue
 			}
 
-			fmt.Fprint(sb, s)
-			if l.NoEOL() {
-				fmt.Fprint(sb, "\n", k_NoEOL)
+			lines, oldStart := h.patchLines()
+			if len(lines) == 0 {
+				continue
 			}
-			return nil
-		},
-	)
+			fmt.Fprintln(sb, h.header(lines, oldStart, offset))
+			oldLines, newLines := lineCounts(lines)
+			offset += newLines - oldLines
+
+			for _, l := range lines {
+				fmt.Fprint(sb, l.String())
+				if l.NoEOL() {
+					fmt.Fprint(sb, "\n", k_NoEOL)
+				}
+			}
+		}
+	}
 
 	return sb.String()
 }
@@ -224,7 +329,11 @@ func (c *Commit) AsPatchString() string {
 func (c *Commit) GetSelectedFiles() []string {
 	ss := make([]string, 0, len(c.Files))
 	for _, file := range c.Files {
-		if file.selection != Deselected {
+		if file.selection == Deselected {
+			continue
+		} else if file.IsDelete {
+			ss = append(ss, file.OldName)
+		} else {
 			ss = append(ss, file.NewName)
 		}
 	}
@@ -237,6 +346,8 @@ type File struct {
 	Expanded   ExpansionState
 	LineNumber int
 	Chunks     []*Chunk
+	// Hidden is set for files that don't match the commit's filter, which aren't displayed.
+	Hidden bool
 }
 
 func (f *File) ToggleSelection() {
@@ -306,25 +417,38 @@ func (file *File) UpdateSelection() {
 func (file *File) Header() string {
 	sb := &strings.Builder{}
 
+	// a partially selected deletion leaves the file in place, so it is written as a modification.
+	isDelete := file.IsDelete && file.selection == Selected
+	newName := file.NewName
+	if file.IsDelete {
+		newName = file.OldName
+	}
+
 	if file.IsNew {
 		fmt.Fprintf(sb, "diff --git a/%s b/%s\n", file.NewName, file.NewName)
-	} else if file.IsDelete {
+	} else if isDelete {
 		fmt.Fprintf(sb, "diff --git a/%s b/%s\n", file.OldName, file.OldName)
 	} else {
-		fmt.Fprintf(sb, "diff --git a/%s b/%s\n", file.OldName, file.NewName)
+		fmt.Fprintf(sb, "diff --git a/%s b/%s\n", file.OldName, newName)
 	}
 
-	if file.IsCopy {
-		fmt.Fprintf(sb, "copy from %s\n", file.OldName)
-		fmt.Fprintf(sb, "copy to %s\n", file.NewName)
-	} else if file.IsRename {
-		fmt.Fprintf(sb, "rename from %s\n", file.OldName)
-		fmt.Fprintf(sb, "rename to %s\n", file.NewName)
+	if file.IsCopy || file.IsRename {
+		if file.IsCopy {
+			fmt.Fprintf(sb, "copy from %s\n", file.OldName)
+			fmt.Fprintf(sb, "copy to %s\n", file.NewName)
+		} else {
+			fmt.Fprintf(sb, "rename from %s\n", file.OldName)
+			fmt.Fprintf(sb, "rename to %s\n", file.NewName)
+		}
+		if len(file.Chunks) > 0 {
+			fmt.Fprintf(sb, "--- a/%s\n", file.OldName)
+			fmt.Fprintf(sb, "+++ b/%s\n", file.NewName)
+		}
 	} else if file.IsNew {
 		fmt.Fprintf(sb, "new file mode %06o\n", file.NewMode)
 		fmt.Fprint(sb, "--- /dev/null\n")
 		fmt.Fprintf(sb, "+++ b/%s\n", file.NewName)
-	} else if file.IsDelete {
+	} else if isDelete {
 		fmt.Fprintf(sb, "deleted file mode %06o\n", file.OldMode)
 		fmt.Fprintf(sb, "--- a/%s\n", file.OldName)
 		fmt.Fprint(sb, "+++ /dev/null\n")
@@ -334,7 +458,7 @@ func (file *File) Header() string {
 			fmt.Fprintf(sb, "new mode %06o\n", file.NewMode)
 		}
 		fmt.Fprintf(sb, "--- a/%s\n", file.OldName)
-		fmt.Fprintf(sb, "+++ b/%s\n", file.NewName)
+		fmt.Fprintf(sb, "+++ b/%s\n", newName)
 		// we leave out object IDs as splits should never need to 3-way merge and the new OID
 		// will be invalid until we create the new commit.
 	}
@@ -349,6 +473,10 @@ type Chunk struct {
 	Parent              *File
 	Lines               []*Line
 	NonContextLineCount int
+	// Edited is set when the lines have been replaced by ApplyEdit.
+	Edited bool
+	// ContextBefore and ContextAfter are the lines shown around the chunk by ShowMoreContext.
+	ContextBefore, ContextAfter []*Line
 }
 
 func (c *Chunk) ToggleSelection() {
@@ -372,6 +500,109 @@ func (c *Chunk) afterSelection() {
 	c.Parent.UpdateSelection()
 }
 
+// Split divides the chunk at the runs of context lines between its changes, like the split command
+// of `git add -p`. The lines of each run are shared out between the chunks before and after it. The
+// new chunks replace the chunk in its parent file and are returned; if the chunk has only one run
+// of changes, it is returned unchanged.
+func (c *Chunk) Split() []*Chunk {
+	// find where each new chunk starts; the first always starts at 0.
+	splitIndices := []int{0}
+	lastChangeIndex := -1
+	for i, l := range c.Lines {
+		if l.Op == gitdiff.OpContext {
+			continue
+		}
+		if lastChangeIndex >= 0 && i-lastChangeIndex > 1 {
+			// the earlier chunk gets the larger half of the context lines.
+			splitIndices = append(splitIndices, i-(i-lastChangeIndex-1)/2)
+		}
+		lastChangeIndex = i
+	}
+	if len(splitIndices) == 1 {
+		return []*Chunk{c}
+	}
+
+	chunks := make([]*Chunk, 0, len(splitIndices))
+	oldStart := c.oldStart()
+	newStart := c.NewPosition
+	if c.NewLines == 0 {
+		newStart++
+	}
+	for i, start := range splitIndices {
+		end := len(c.Lines)
+		if i+1 < len(splitIndices) {
+			end = splitIndices[i+1]
+		}
+		chunk := c.subChunk(c.Lines[start:end], oldStart, newStart)
+		chunks = append(chunks, chunk)
+		oldStart += chunk.OldLines
+		newStart += chunk.NewLines
+	}
+
+	for i, chunk := range c.Parent.Chunks {
+		if chunk == c {
+			c.Parent.Chunks = append(c.Parent.Chunks[:i], append(chunks, c.Parent.Chunks[i+1:]...)...)
+			break
+		}
+	}
+	return chunks
+}
+
+// subChunk creates a chunk from a subset of the chunk's lines, where oldStart and newStart are the
+// first line numbers of the subset in the old and new files.
+func (c *Chunk) subChunk(lines []*Line, oldStart, newStart int64) *Chunk {
+	chunk := &Chunk{Expanded: c.Expanded, Parent: c.Parent, Edited: c.Edited}
+	chunk.setLines(lines, c.Comment, oldStart, newStart)
+	return chunk
+}
+
+// setLines replaces the lines of the chunk and its fragment, where oldStart and newStart are the
+// first line numbers of the lines in the old and new files.
+func (c *Chunk) setLines(lines []*Line, comment string, oldStart, newStart int64) {
+	fragment := &gitdiff.TextFragment{Comment: comment}
+	c.TextFragment = fragment
+	c.Lines = make([]*Line, 0, len(lines))
+	c.NonContextLineCount = 0
+	for _, l := range lines {
+		l.Parent = c
+		c.Lines = append(c.Lines, l)
+		fragment.Lines = append(fragment.Lines, l.Line)
+		switch l.Op {
+		case gitdiff.OpContext:
+			fragment.OldLines++
+			fragment.NewLines++
+			if fragment.LinesAdded == 0 && fragment.LinesDeleted == 0 {
+				fragment.LeadingContext++
+			} else {
+				fragment.TrailingContext++
+			}
+			continue
+		case gitdiff.OpDelete:
+			fragment.OldLines++
+			fragment.LinesDeleted++
+		case gitdiff.OpAdd:
+			fragment.NewLines++
+			fragment.LinesAdded++
+		}
+		// context between changes isn't trailing context.
+		fragment.TrailingContext = 0
+		c.NonContextLineCount++
+	}
+
+	// a zero-length range refers to the line before the range instead of the first line in it.
+	fragment.OldPosition = oldStart
+	if fragment.OldLines == 0 {
+		fragment.OldPosition--
+	}
+	fragment.NewPosition = newStart
+	if fragment.NewLines == 0 {
+		fragment.NewPosition--
+	}
+
+	c.pairLines()
+	c.UpdateSelection()
+}
+
 func (chunk *Chunk) ForEachNode(lfn LineFunc) error {
 	for _, line := range chunk.Lines {
 		if lfn != nil {
@@ -404,7 +635,10 @@ func (chunk *Chunk) UpdateSelection() {
 			return nil
 		},
 	)
-	if selectedLineCount == chunk.NonContextLineCount {
+	if chunk.NonContextLineCount == 0 {
+		// only an edited chunk can have no changes, and there's nothing in it to select.
+		chunk.selection = Deselected
+	} else if selectedLineCount == chunk.NonContextLineCount {
 		chunk.selection = Selected
 	} else if selectedLineCount > 0 || partiallySelectedLineCount > 0 {
 		chunk.selection = PartiallySelected
@@ -418,6 +652,10 @@ type Line struct {
 	gitdiff.Line
 	selection SelectionState
 	Parent    *Chunk
+	// Pair is set if the line is a deletion replaced by an addition, or vice versa.
+	Pair *LinePair
+	// Extra is set on the context lines shown by ShowMoreContext, which aren't part of the diff.
+	Extra bool
 }
 
 func (l *Line) ToggleSelection() {
@@ -453,6 +691,7 @@ func ParseCommit(r io.Reader) (commit *Commit, err error) {
 					outChunk.NonContextLineCount++
 				}
 			}
+			outChunk.pairLines()
 		}
 	}
 	return commit, nil
@@ -0,0 +1,145 @@
+package difftree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkSplit(t *testing.T) {
+	type wantChunk struct {
+		oldPosition, oldLines, newPosition, newLines int64
+		lines                                        string
+	}
+	tests := []struct {
+		name  string
+		chunk string
+		want  []wantChunk
+	}{
+		{
+			name: "context shared evenly",
+			chunk: `@@ -1,6 +1,6 @@
+-a
++A
+ b
+ c
+ d
+ e
+-f
++F
+`,
+			want: []wantChunk{
+				{1, 3, 1, 3, "-a\n+A\n b\n c\n"},
+				{4, 3, 4, 3, " d\n e\n-f\n+F\n"},
+			},
+		},
+		{
+			name: "larger half of the context before the split",
+			chunk: `@@ -1,5 +1,6 @@
++x
++y
+ a
+ b
+ c
+-d
+ e
+`,
+			want: []wantChunk{
+				{1, 2, 1, 4, "+x\n+y\n a\n b\n"},
+				{3, 3, 5, 2, " c\n-d\n e\n"},
+			},
+		},
+		{
+			name: "start positions later in the file",
+			chunk: `@@ -20,5 +22,6 @@
++x
++y
+ a
+ b
+ c
+-d
+ e
+`,
+			want: []wantChunk{
+				{20, 2, 22, 4, "+x\n+y\n a\n b\n"},
+				{22, 3, 26, 2, " c\n-d\n e\n"},
+			},
+		},
+		{
+			name: "new chunk without new lines",
+			chunk: `@@ -1,3 +1,1 @@
+-a
+ b
+-c
+`,
+			want: []wantChunk{
+				{1, 2, 1, 1, "-a\n b\n"},
+				{3, 1, 1, 0, "-c\n"},
+			},
+		},
+		{
+			name: "new chunk without old lines",
+			chunk: `@@ -1,1 +1,3 @@
++a
+ b
++c
+`,
+			want: []wantChunk{
+				{1, 1, 1, 2, "+a\n b\n"},
+				{1, 0, 3, 1, "+c\n"},
+			},
+		},
+		{
+			name: "chunk without new lines",
+			chunk: `@@ -1,2 +0,0 @@
+-a
+-b
+`,
+			want: []wantChunk{
+				{1, 2, 0, 0, "-a\n-b\n"},
+			},
+		},
+		{
+			name: "single change",
+			chunk: `@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+			want: []wantChunk{
+				{1, 3, 1, 3, " a\n-b\n+B\n c\n"},
+			},
+		},
+	}
+	for _, test := range tests {
+		commit := parseTestCommit(t, "diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n"+test.chunk)
+		file := commit.Files[0]
+		original := file.Chunks[0]
+		chunks := original.Split()
+		if len(chunks) != len(test.want) {
+			t.Errorf("%s: split into %d chunks, not %d", test.name, len(chunks), len(test.want))
+			continue
+		} else if len(chunks) == 1 && chunks[0] != original {
+			t.Errorf("%s: the chunk was replaced without being split", test.name)
+		}
+		if len(file.Chunks) != len(chunks) {
+			t.Errorf("%s: the file has %d chunks, not %d", test.name, len(file.Chunks), len(chunks))
+		}
+		for i, c := range chunks {
+			lines := &strings.Builder{}
+			for _, l := range c.Lines {
+				lines.WriteString(l.Line.String())
+				if l.Parent != c {
+					t.Errorf("%s: chunk %d: line %q has the wrong parent", test.name, i+1, l.Line.String())
+				}
+			}
+			got := wantChunk{c.OldPosition, c.OldLines, c.NewPosition, c.NewLines, lines.String()}
+			if got != test.want[i] {
+				t.Errorf("%s: chunk %d is %+v, not %+v", test.name, i+1, got, test.want[i])
+			}
+			if i < len(file.Chunks) && file.Chunks[i] != c {
+				t.Errorf("%s: chunk %d isn't in the file", test.name, i+1)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package difftree
+
+import (
+	"fmt"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// k_PatchContext is the most context lines written before and after the changes of a hunk, which
+// matches Git's default. patch(1) expects a hunk with less context on one side to be at the start
+// or end of the file, so turning deselected deletions into context mustn't unbalance it.
+const k_PatchContext = 3
+
+// hunk is a run of chunks that directly follow each other in the old file, such as the chunks
+// created by splitting a chunk. A hunk is written to a patch as a single chunk so that the chunks in
+// it share their context lines; a deselected chunk in a hunk is written as context only.
+type hunk []*Chunk
+
+// hunks groups the chunks of the file into hunks.
+func (file *File) hunks() []hunk {
+	hunks := make([]hunk, 0, len(file.Chunks))
+	for i, chunk := range file.Chunks {
+		if i > 0 && file.Chunks[i-1].oldEnd() == chunk.oldStart() {
+			hunks[len(hunks)-1] = append(hunks[len(hunks)-1], chunk)
+		} else {
+			hunks = append(hunks, hunk{chunk})
+		}
+	}
+	return hunks
+}
+
+// oldStart returns the first line of the chunk in the old file. For chunks without lines in the old
+// file, this is the line the chunk is inserted before.
+func (c *Chunk) oldStart() int64 {
+	if c.OldLines == 0 {
+		return c.OldPosition + 1
+	}
+	return c.OldPosition
+}
+
+// oldEnd returns the line after the last line of the chunk in the old file.
+func (c *Chunk) oldEnd() int64 {
+	return c.oldStart() + c.OldLines
+}
+
+func (h hunk) selection() SelectionState {
+	selection := h[0].selection
+	for _, chunk := range h[1:] {
+		if chunk.selection != selection {
+			return PartiallySelected
+		}
+	}
+	return selection
+}
+
+// patchLines returns the lines of the hunk as they will be written by AsPatchString. Deselected
+// additions are dropped and deselected deletions become context, then the context at either end is
+// trimmed to k_PatchContext lines. oldStart is the first line of the result in the old file. If no
+// changes are left, no lines are returned.
+func (h hunk) patchLines() (lines []gitdiff.Line, oldStart int64) {
+	for _, chunk := range h {
+		for _, l := range chunk.Lines {
+			line := l.Line
+			if l.selection == Deselected {
+				if l.Op == gitdiff.OpAdd {
+					continue
+				} else if l.Op == gitdiff.OpDelete {
+					// removing OpDeletes makes the patch fail, so we change them into context lines
+					// for patches.
+					line.Op = gitdiff.OpContext
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	oldStart = h[0].oldStart()
+	leadingContext := 0
+	for leadingContext < len(lines) && lines[leadingContext].Op == gitdiff.OpContext {
+		leadingContext++
+	}
+	if leadingContext == len(lines) {
+		return nil, 0
+	}
+	trailingContext := 0
+	for trailingContext < len(lines) && lines[len(lines)-1-trailingContext].Op == gitdiff.OpContext {
+		trailingContext++
+	}
+	if trailingContext > k_PatchContext {
+		lines = lines[:len(lines)-trailingContext+k_PatchContext]
+	}
+	if leadingContext > k_PatchContext {
+		lines = lines[leadingContext-k_PatchContext:]
+		oldStart += int64(leadingContext - k_PatchContext)
+	}
+	return lines, oldStart
+}
+
+// header returns the header for the lines and old start position returned by patchLines. offset is
+// the number of lines that the hunks before this one in the same file add (or remove, if negative)
+// in the new file.
+func (h hunk) header(lines []gitdiff.Line, oldStart, offset int64) string {
+	oldLines, newLines := lineCounts(lines)
+
+	// a zero-length range refers to the line before the range instead of the first line in it.
+	oldPosition := oldStart
+	newPosition := oldStart + offset
+	if oldLines == 0 {
+		oldPosition--
+	}
+	if newLines == 0 {
+		newPosition--
+	}
+
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldPosition, oldLines, newPosition, newLines)
+	if len(h[0].Comment) > 0 {
+		header += " " + h[0].Comment
+	}
+	return header
+}
+
+// lineCounts returns the number of lines in the old and new files that the lines cover.
+func lineCounts(lines []gitdiff.Line) (oldLines, newLines int64) {
+	for _, l := range lines {
+		if l.Old() {
+			oldLines++
+		}
+		if l.New() {
+			newLines++
+		}
+	}
+	return oldLines, newLines
+}
@@ -0,0 +1,303 @@
+package difftree
+
+import (
+	"testing"
+)
+
+func TestAsPatchStringHeaders(t *testing.T) {
+	type linePosition struct{ file, chunk, line int }
+	tests := []struct {
+		name     string
+		patch    string
+		deselect []linePosition
+		want     string
+	}{
+		{
+			name: "all selected",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,5 @@
+ a
++x
++y
+ b
+ c
+@@ -10,3 +12,3 @@
+ j
+-k
++K
+ l
+`,
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,5 @@
+ a
++x
++y
+ b
+ c
+@@ -10,3 +12,3 @@
+ j
+-k
++K
+ l
+`,
+		},
+		{
+			name: "new start after dropped adds",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,5 @@
+ a
++x
++y
+ b
+ c
+@@ -10,3 +12,3 @@
+ j
+-k
++K
+ l
+`,
+			deselect: []linePosition{{0, 0, 1}},
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,4 @@
+ a
++y
+ b
+ c
+@@ -10,3 +11,3 @@
+ j
+-k
++K
+ l
+`,
+		},
+		{
+			name: "new start after a deselected chunk",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,5 @@
+ a
++x
++y
+ b
+ c
+@@ -10,3 +12,3 @@
+ j
+-k
++K
+ l
+`,
+			deselect: []linePosition{{0, 0, 1}, {0, 0, 2}},
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -10,3 +10,3 @@
+ j
+-k
++K
+ l
+`,
+		},
+		{
+			name: "deletes turned into context",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,5 +1,3 @@
+ a
+-b
+-c
+ d
+ e
+`,
+			deselect: []linePosition{{0, 0, 1}},
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,5 +1,4 @@
+ a
+ b
+-c
+ d
+ e
+`,
+		},
+		{
+			name: "deletes turned into context trimmed from the start",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,6 +1,3 @@
+ a
+ b
+-c
+-d
+-e
+ f
+`,
+			deselect: []linePosition{{0, 0, 2}, {0, 0, 3}},
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -2,5 +2,4 @@
+ b
+ c
+ d
+-e
+ f
+`,
+		},
+		{
+			name: "zero-length old range",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -0,0 +1,2 @@
++x
++y
+`,
+			deselect: []linePosition{{0, 0, 0}},
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -0,0 +1,1 @@
++y
+`,
+		},
+		{
+			name: "zero-length new range",
+			patch: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+			want: `diff --git a/f.txt b/f.txt
+--- a/f.txt
++++ b/f.txt
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+		},
+		{
+			name: "new file",
+			patch: `diff --git a/n.txt b/n.txt
+new file mode 100644
+index 0000000..2222222
+--- /dev/null
++++ b/n.txt
+@@ -0,0 +1,2 @@
++x
++y
+`,
+			deselect: []linePosition{{0, 0, 0}},
+			want: `diff --git a/n.txt b/n.txt
+new file mode 100644
+--- /dev/null
++++ b/n.txt
+@@ -0,0 +1,1 @@
++y
+`,
+		},
+		{
+			name: "deleted file",
+			patch: `diff --git a/d.txt b/d.txt
+deleted file mode 100644
+index 1111111..0000000
+--- a/d.txt
++++ /dev/null
+@@ -1,3 +0,0 @@
+-a
+-b
+-c
+`,
+			want: `diff --git a/d.txt b/d.txt
+deleted file mode 100644
+--- a/d.txt
++++ /dev/null
+@@ -1,3 +0,0 @@
+-a
+-b
+-c
+`,
+		},
+		{
+			name: "partial delete",
+			patch: `diff --git a/d.txt b/d.txt
+deleted file mode 100644
+index 1111111..0000000
+--- a/d.txt
++++ /dev/null
+@@ -1,3 +0,0 @@
+-a
+-b
+-c
+`,
+			deselect: []linePosition{{0, 0, 1}},
+			want: `diff --git a/d.txt b/d.txt
+--- a/d.txt
++++ b/d.txt
+@@ -1,3 +1,1 @@
+-a
+ b
+-c
+`,
+		},
+		{
+			name: "rename",
+			patch: `diff --git a/old.txt b/new.txt
+similarity index 80%
+rename from old.txt
+rename to new.txt
+index 1111111..2222222 100644
+--- a/old.txt
++++ b/new.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+			deselect: []linePosition{{0, 0, 2}},
+			want: `diff --git a/old.txt b/new.txt
+rename from old.txt
+rename to new.txt
+--- a/old.txt
++++ b/new.txt
+@@ -1,3 +1,2 @@
+ a
+-b
+ c
+`,
+		},
+	}
+	for _, test := range tests {
+		commit := parseTestCommit(t, test.patch)
+		for _, f := range commit.Files {
+			f.SetSelection(Selected)
+		}
+		for _, p := range test.deselect {
+			commit.Files[p.file].Chunks[p.chunk].Lines[p.line].SetSelection(Deselected)
+		}
+		if patch := commit.AsPatchString(); patch != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, patch, test.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package difftree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/fatih/color"
+)
+
+const k_PairTop = " ┌ "
+const k_PairBottom = " └ "
+
+// k_AddBackground and k_DeleteBackground are the 256-colour backgrounds of added and deleted lines,
+// which are dark enough for the syntax colours of their text to show on them.
+const k_AddBackground = 22
+const k_DeleteBackground = 52
+
+// LinePair is a deleted line and the added line that replaces it. Selecting the pair selects both,
+// so that a modified line can be moved between commits as a unit.
+type LinePair struct {
+	Delete *Line
+	Add    *Line
+
+	// the changed words of each line, which are found when the pair is first displayed.
+	deleteChanges, addChanges []span
+	isDiffed                  bool
+}
+
+func (p *LinePair) Selection() SelectionState {
+	if p.Delete.selection == p.Add.selection {
+		return p.Delete.selection
+	}
+	return PartiallySelected
+}
+
+func (p *LinePair) ToggleSelection() {
+	selection := p.Selection()
+	selection.Toggle()
+	p.SetSelection(selection)
+}
+
+func (p *LinePair) SetSelection(state SelectionState) {
+	if state == PartiallySelected {
+		panic(k_PanicPartialSelection)
+	}
+	p.Delete.selection = state
+	p.Add.selection = state
+	p.Delete.Parent.UpdateSelection()
+}
+
+// pairLines pairs up the deleted and added lines of each run of changes in the chunk that has as
+// many deletions as additions, which is how modified lines usually appear. Like Git's
+// diff-highlight, runs with different counts are left alone, as there's no telling which lines
+// replace which. Each pair is moved together in the run, which doesn't change the patch.
+func (c *Chunk) pairLines() {
+	for start := 0; start < len(c.Lines); {
+		if c.Lines[start].Op == gitdiff.OpContext {
+			start++
+			continue
+		}
+
+		end := start
+		var deletes, adds []*Line
+		for ; end < len(c.Lines) && c.Lines[end].Op != gitdiff.OpContext; end++ {
+			l := c.Lines[end]
+			l.Pair = nil
+			if l.Op == gitdiff.OpDelete {
+				deletes = append(deletes, l)
+			} else {
+				adds = append(adds, l)
+			}
+		}
+
+		if len(deletes) == len(adds) {
+			for i := range deletes {
+				pair := &LinePair{Delete: deletes[i], Add: adds[i]}
+				deletes[i].Pair = pair
+				adds[i].Pair = pair
+				c.Lines[start+2*i] = deletes[i]
+				c.Lines[start+2*i+1] = adds[i]
+			}
+		}
+		start = end
+	}
+}
+
+// changes returns the spans of the line that differ from the other line of its pair.
+func (p *LinePair) changes(l *Line) []span {
+	if !p.isDiffed {
+		p.deleteChanges, p.addChanges = wordDiff(
+			[]rune(strings.TrimSuffix(p.Delete.Line.Line, "\n")),
+			[]rune(strings.TrimSuffix(p.Add.Line.Line, "\n")),
+		)
+		p.isDiffed = true
+	}
+
+	if l == p.Delete {
+		return p.deleteChanges
+	}
+	return p.addChanges
+}
+
+// styled returns the rune in the style of the line: a background that shows whether it is added or
+// deleted, and faint text if it is only extra context.
+func (l *Line) styled(r rune) styledRune {
+	styled := styledRune{r: r, color: color.FgWhite}
+	if l.Extra {
+		styled.color = color.Faint
+	}
+	switch l.Op {
+	case gitdiff.OpAdd:
+		styled.background = k_AddBackground
+	case gitdiff.OpDelete:
+		styled.background = k_DeleteBackground
+	}
+	return styled
+}
+
+// styledRune is a rune of a line and how it is shown. background is a 256-colour index, or 0 for
+// none.
+type styledRune struct {
+	r          rune
+	color      color.Attribute
+	background int
+	reversed   bool
+}
+
+// styledRunes returns the runes of the line, without its end of line, in the style of the line.
+// Tokens are highlighted with syn if it isn't nil and the line is part of the diff, and if the line
+// is paired, the words that differ from the other line of the pair are reversed.
+func (l *Line) styledRunes(syn syntax) []styledRune {
+	runes := []rune(strings.TrimSuffix(l.Line.Line, "\n"))
+	styled := make([]styledRune, len(runes))
+	for i, r := range runes {
+		styled[i] = l.styled(r)
+	}
+	if syn != nil && !l.Extra {
+		for _, token := range syn.highlight(runes) {
+			for i := token.start; i < token.end; i++ {
+				styled[i].color = token.color
+			}
+		}
+	}
+	if l.Pair != nil {
+		for _, change := range l.Pair.changes(l) {
+			for i := change.start; i < change.end; i++ {
+				styled[i].reversed = true
+			}
+		}
+	}
+	return styled
+}
+
+// writeStyledRunes writes the runes with the escape codes for their styles, and resets the style at
+// the end.
+func writeStyledRunes(sb *strings.Builder, runes []styledRune) {
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && runes[end].color == runes[start].color &&
+			runes[end].background == runes[start].background && runes[end].reversed == runes[start].reversed {
+			end++
+		}
+		// reverse is only cleared by a reset, so every run starts with one.
+		fmt.Fprintf(sb, "\u001b[0m\u001b[%dm", runes[start].color)
+		if runes[start].background != 0 {
+			fmt.Fprintf(sb, "\u001b[48;5;%dm", runes[start].background)
+		}
+		if runes[start].reversed {
+			fmt.Fprint(sb, "\u001b[7m")
+		}
+		for _, r := range runes[start:end] {
+			sb.WriteRune(r.r)
+		}
+		start = end
+	}
+	fmt.Fprint(sb, "\u001b[0m")
+}
+
+// displayRunes returns the line as it is displayed: its op, then its runes styled as described by
+// styledRunes, with tabs expanded.
+func (l *Line) displayRunes(syn syntax) []styledRune {
+	op := l.styled([]rune(l.Op.String())[0])
+	return append([]styledRune{op}, expandTabs(l.styledRunes(syn))...)
+}
@@ -0,0 +1,141 @@
+package difftree
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// Position is where a node is in the new file, which is the same in every diff against the same
+// target, so that it can be found again after part of the diff has been committed.
+type Position struct {
+	path string
+	// line is the line of the chunk or line in the new file, or 0 for the file itself.
+	line  int64
+	chunk bool
+}
+
+// path returns the name of the file in the new file, or the old one for deleted files.
+func (f *File) path() string {
+	if f.IsDelete {
+		return f.OldName
+	}
+	return f.NewName
+}
+
+// newStart returns the first line of the chunk in the new file. For chunks without lines in the new
+// file, this is the line they were deleted before.
+func (c *Chunk) newStart() int64 {
+	if c.NewLines == 0 {
+		return c.NewPosition + 1
+	}
+	return c.NewPosition
+}
+
+// newLineNumbers returns the line number of each line of the chunk in the new file, where a deleted
+// line has the number of the line after it.
+func (c *Chunk) newLineNumbers() []int64 {
+	numbers := make([]int64, len(c.Lines))
+	number := c.newStart()
+	for i, l := range c.Lines {
+		numbers[i] = number
+		if l.Op != gitdiff.OpDelete {
+			number++
+		}
+	}
+	return numbers
+}
+
+// overlaps returns whether the chunks have lines in common in the new file, counting the lines at
+// either end, so that chunks without lines in the new file overlap where they were.
+func (c *Chunk) overlaps(other *Chunk) bool {
+	return c.newStart() <= other.newStart()+other.NewLines && other.newStart() <= c.newStart()+c.NewLines
+}
+
+// PositionOf returns the position of a file, chunk, line or line pair.
+func PositionOf(node Selectable) Position {
+	switch node := node.(type) {
+	case *File:
+		return Position{path: node.path()}
+	case *Chunk:
+		return Position{path: node.Parent.path(), line: node.newStart(), chunk: true}
+	case *LinePair:
+		return PositionOf(node.Delete)
+	case *Line:
+		for i, l := range node.Parent.Lines {
+			if l == node {
+				return Position{path: node.Parent.Parent.path(), line: node.Parent.newLineNumbers()[i]}
+			}
+		}
+	}
+	return Position{}
+}
+
+// Find returns the node shown at the position, which is the line at it, the chunk containing it if
+// the chunk is collapsed or the line is gone, or the file, or nil if the file isn't shown.
+func (commit *Commit) Find(p Position) Selectable {
+	for _, f := range commit.Files {
+		if f.Hidden || f.path() != p.path {
+			continue
+		} else if p.line == 0 || f.Expanded == Collapsed {
+			return f
+		}
+
+		for _, c := range f.Chunks {
+			if p.line < c.newStart() || p.line > c.newStart()+c.NewLines {
+				continue
+			} else if p.chunk || c.Expanded == Collapsed {
+				return c
+			}
+			// a changed line is preferred over the context at the same line.
+			var found Selectable = c
+			for i, number := range c.newLineNumbers() {
+				if number == p.line {
+					found = c.Lines[i]
+					if c.Lines[i].Op != gitdiff.OpContext {
+						return found
+					}
+				}
+			}
+			return found
+		}
+		return f
+	}
+	return nil
+}
+
+// CarryExpansion expands the files and chunks that were expanded in the other commit, which is an
+// earlier diff against the same target. A chunk is expanded if any chunk it overlaps in the new file
+// was. Files and chunks that weren't in it keep their expansion.
+func (commit *Commit) CarryExpansion(other *Commit) {
+	otherFiles := map[string]*File{}
+	for _, f := range other.Files {
+		otherFiles[f.path()] = f
+	}
+
+	for _, f := range commit.Files {
+		otherFile, ok := otherFiles[f.path()]
+		if !ok {
+			continue
+		}
+		f.Expanded = otherFile.Expanded
+		for _, c := range f.Chunks {
+			for _, otherChunk := range otherFile.Chunks {
+				if c.overlaps(otherChunk) {
+					c.Expanded = otherChunk.Expanded
+					if c.Expanded == Expanded {
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// SetExpansion sets the expansion of every file and chunk.
+func (commit *Commit) SetExpansion(files, chunks ExpansionState) {
+	for _, f := range commit.Files {
+		f.Expanded = files
+		for _, c := range f.Chunks {
+			c.Expanded = chunks
+		}
+	}
+}
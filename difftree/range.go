@@ -0,0 +1,60 @@
+package difftree
+
+// RangeSelection returns the combined selection state of the nodes, which may be files, chunks, lines
+// or line pairs: Selected or Deselected if they all are, or PartiallySelected otherwise. An empty
+// range is Deselected.
+func RangeSelection(nodes []Selectable) SelectionState {
+	state := Deselected
+	for i, node := range nodes {
+		var s SelectionState
+		switch node := node.(type) {
+		case *File:
+			s = node.selection
+		case *Chunk:
+			s = node.selection
+		case *Line:
+			s = node.selection
+		case *LinePair:
+			s = node.Selection()
+		}
+
+		if i == 0 {
+			state = s
+		} else if s != state {
+			return PartiallySelected
+		}
+	}
+	return state
+}
+
+// SetRangeSelection sets the selection of the nodes, which may be files, chunks, lines or line pairs,
+// like calling SetSelection on each, but updates the chunks containing lines only once.
+func SetRangeSelection(nodes []Selectable, state SelectionState) {
+	if state == PartiallySelected {
+		panic(k_PanicPartialSelection)
+	}
+
+	chunks := []*Chunk{}
+	changed := map[*Chunk]bool{}
+	setLine := func(l *Line) {
+		l.selection = state
+		if !changed[l.Parent] {
+			changed[l.Parent] = true
+			chunks = append(chunks, l.Parent)
+		}
+	}
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *Line:
+			setLine(node)
+		case *LinePair:
+			setLine(node.Delete)
+			setLine(node.Add)
+		default:
+			node.SetSelection(state)
+		}
+	}
+	for _, c := range chunks {
+		c.UpdateSelection()
+	}
+}
@@ -0,0 +1,98 @@
+package difftree
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// fileKey identifies a file across diffs of the same changes.
+type fileKey struct {
+	oldName, newName string
+}
+
+// lineKey identifies a changed line of a file across diffs of the same changes: a deleted line by its
+// number in the old file, and an added line by its number in the new file.
+type lineKey struct {
+	op     gitdiff.LineOp
+	number int64
+}
+
+func (f *File) key() fileKey {
+	return fileKey{f.OldName, f.NewName}
+}
+
+// changedLines returns the changed lines of the file by their keys.
+func (f *File) changedLines() map[lineKey]*Line {
+	lines := map[lineKey]*Line{}
+	for _, c := range f.Chunks {
+		oldNumber, newNumber := c.oldStart(), c.newStart()
+		for _, l := range c.Lines {
+			switch l.Op {
+			case gitdiff.OpContext:
+				oldNumber++
+				newNumber++
+			case gitdiff.OpDelete:
+				lines[lineKey{l.Op, oldNumber}] = l
+				oldNumber++
+			case gitdiff.OpAdd:
+				lines[lineKey{l.Op, newNumber}] = l
+				newNumber++
+			}
+		}
+	}
+	return lines
+}
+
+// Rediff replaces the files of the commit with those of other, a diff of the same changes made with
+// different options, keeping the description and filter. Lines that are changed in both keep their
+// selection. Other lines, and files without chunks, take the selection of their file if it was fully
+// selected or deselected, and are deselected otherwise. Files stay expanded, with all of their chunks.
+func (commit *Commit) Rediff(other *Commit) {
+	oldFiles := map[fileKey]*File{}
+	for _, f := range commit.Files {
+		oldFiles[f.key()] = f
+	}
+
+	for _, f := range other.Files {
+		oldFile, ok := oldFiles[f.key()]
+		if !ok {
+			continue
+		}
+		defaultState := oldFile.selection
+		if defaultState == PartiallySelected {
+			defaultState = Deselected
+		}
+
+		f.Expanded = oldFile.Expanded
+		if len(f.Chunks) == 0 {
+			f.selection = defaultState
+			continue
+		}
+		oldLines := oldFile.changedLines()
+		for key, l := range f.changedLines() {
+			if oldLine, ok := oldLines[key]; ok {
+				l.selection = oldLine.selection
+			} else {
+				l.selection = defaultState
+			}
+		}
+		for _, c := range f.Chunks {
+			c.Expanded = f.Expanded
+			c.UpdateSelection()
+		}
+	}
+
+	commit.Files = other.Files
+	commit.SetFilter(commit.Filter)
+}
+
+// HasEditedChunks returns whether any chunk has been edited with ApplyEdit.
+func (commit *Commit) HasEditedChunks() bool {
+	for _, f := range commit.Files {
+		for _, c := range f.Chunks {
+			if c.Edited {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package difftree
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// SelectPart selects the changes a part made, and deselects the rest. part is the diff the part made
+// from the same old files as the commit, and remainder the diff from the part to the same new files,
+// so deleted lines are in the part if it deleted them, and added lines if they don't remain to be
+// added after it. Files without chunks are selected if the part changed them.
+func (commit *Commit) SelectPart(part, remainder *Commit) {
+	partFiles := map[fileKey]*File{}
+	for _, f := range part.Files {
+		// a file is found by either name, as the commit may also rename it, or create or delete it.
+		if len(f.OldName) > 0 {
+			partFiles[fileKey{f.OldName, ""}] = f
+		}
+		if len(f.NewName) > 0 {
+			partFiles[fileKey{"", f.NewName}] = f
+		}
+	}
+	remainderFiles := map[string]*File{}
+	for _, f := range remainder.Files {
+		remainderFiles[f.NewName] = f
+	}
+
+	for _, f := range commit.Files {
+		partFile := partFiles[fileKey{f.OldName, ""}]
+		if partFile == nil && len(f.NewName) > 0 {
+			partFile = partFiles[fileKey{"", f.NewName}]
+		}
+		if len(f.Chunks) == 0 {
+			f.selection = Deselected
+			if partFile != nil {
+				f.selection = Selected
+			}
+			continue
+		}
+
+		deleted := map[lineKey]*Line{}
+		if partFile != nil {
+			deleted = partFile.changedLines()
+		}
+		remaining := map[lineKey]*Line{}
+		if remainderFile := remainderFiles[f.NewName]; remainderFile != nil {
+			remaining = remainderFile.changedLines()
+		}
+		for key, l := range f.changedLines() {
+			l.selection = Deselected
+			if _, ok := deleted[key]; ok && l.Op == gitdiff.OpDelete {
+				l.selection = Selected
+			} else if _, ok := remaining[key]; !ok && l.Op == gitdiff.OpAdd {
+				l.selection = Selected
+			}
+		}
+		for _, c := range f.Chunks {
+			c.UpdateSelection()
+		}
+	}
+}
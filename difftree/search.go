@@ -0,0 +1,119 @@
+package difftree
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// matches returns whether re matches the text of the node: the names of a file, the header of a
+// chunk or the text of a line.
+func matches(re *regexp.Regexp, node Selectable) bool {
+	switch node := node.(type) {
+	case *File:
+		return re.MatchString(node.OldName) || re.MatchString(node.NewName)
+	case *Chunk:
+		return re.MatchString(node.Header())
+	case *Line:
+		return re.MatchString(strings.TrimSuffix(node.Line.Line, "\n"))
+	case *LinePair:
+		return matches(re, node.Delete) || matches(re, node.Add)
+	}
+	return false
+}
+
+// Search returns the next node after from that re matches, or the previous one if backward is true,
+// wrapping around the commit. Nodes in collapsed files and chunks are searched too, but not those in
+// files hidden by the filter. It returns nil if nothing matches, and searches from the start of the
+// commit if from is nil.
+func (commit *Commit) Search(re *regexp.Regexp, from Selectable, backward bool) Selectable {
+	// a pair is searched from its deleted line, and matches in the pair itself are skipped, as they
+	// are on the same row of the side-by-side layout.
+	pair, _ := from.(*LinePair)
+	if pair != nil {
+		from = pair.Delete
+	}
+
+	nodes := []Selectable{}
+	start := -1
+	commit.ForEachShownNode(
+		func(f *File) error {
+			nodes = append(nodes, f)
+			return nil
+		},
+		func(_ *File, c *Chunk) error {
+			nodes = append(nodes, c)
+			return nil
+		},
+		func(_ *File, _ *Chunk, l *Line) error {
+			nodes = append(nodes, l)
+			return nil
+		},
+	)
+	for i, node := range nodes {
+		if node == from {
+			start = i
+		}
+	}
+
+	step := 1
+	if backward {
+		step = -1
+		if start < 0 {
+			start = len(nodes)
+		}
+	}
+	for n := 1; n <= len(nodes); n++ {
+		i := ((start+step*n)%len(nodes) + len(nodes)) % len(nodes)
+		if pair != nil && (nodes[i] == pair.Delete || nodes[i] == pair.Add) {
+			continue
+		} else if matches(re, nodes[i]) {
+			return nodes[i]
+		}
+	}
+	return nil
+}
+
+// SetSelectionMatching sets the selection of every added or deleted line that re matches, except in
+// files hidden by the filter, and updates the chunks and files containing them. If any ops are given,
+// only lines with one of them are changed. It returns the number of lines that were changed.
+func (commit *Commit) SetSelectionMatching(re *regexp.Regexp, state SelectionState, ops ...gitdiff.LineOp) int {
+	if state == PartiallySelected {
+		panic(k_PanicPartialSelection)
+	}
+
+	count := 0
+	for _, f := range commit.Files {
+		if f.Hidden {
+			continue
+		}
+		for _, c := range f.Chunks {
+			chunkCount := 0
+			for _, l := range c.Lines {
+				if l.Op == gitdiff.OpContext || !hasOp(ops, l.Op) || !matches(re, l) {
+					continue
+				}
+				l.selection = state
+				chunkCount++
+			}
+			if chunkCount > 0 {
+				c.UpdateSelection()
+				count += chunkCount
+			}
+		}
+	}
+	return count
+}
+
+func hasOp(ops []gitdiff.LineOp, op gitdiff.LineOp) bool {
+	if len(ops) == 0 {
+		return true
+	}
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
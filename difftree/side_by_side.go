@@ -0,0 +1,111 @@
+package difftree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/mattn/go-runewidth"
+)
+
+const k_ColumnSeparator = " │ "
+const k_Truncated = '…'
+const k_MinColumnWidth = 8
+
+// SideBySideString is like String, but shows the lines of expanded chunks in columns for the old
+// and new file, fitting in width cells. A modified line pair is shown on one row, which maps to the
+// *LinePair in the line map, while other changed lines have a row of their own.
+func (commit *Commit) SideBySideString(width int) string {
+	prefixWidth := 2*len(k_DisplayTab) + 2*len(k_MissingSpacer) + 2
+	columnWidth := (width - prefixWidth - runewidth.StringWidth(k_ColumnSeparator)) / 2
+	if columnWidth < k_MinColumnWidth {
+		columnWidth = k_MinColumnWidth
+	}
+
+	return commit.render(func(sb *strings.Builder, f *File, l *Line) {
+		var node Selectable = l
+		selection := l.selection
+		left, right := l, l
+		switch {
+		case l.Pair != nil && l == l.Pair.Add:
+			// already shown with the deleted line.
+			return
+		case l.Pair != nil:
+			node = l.Pair
+			selection = l.Pair.Selection()
+			right = l.Pair.Add
+		case l.Op == gitdiff.OpDelete:
+			right = nil
+		case l.Op == gitdiff.OpAdd:
+			left = nil
+		}
+		commit.LineMap = append(commit.LineMap, node)
+
+		fmt.Fprint(sb, k_DisplayTab, k_DisplayTab, k_MissingSpacer, " ")
+		if l.Op == gitdiff.OpContext {
+			fmt.Fprint(sb, k_MissingSpacer)
+		} else {
+			fmt.Fprint(sb, selection.String())
+		}
+		fmt.Fprint(sb, " ")
+		writeColumn(sb, left, f.language(), columnWidth)
+		fmt.Fprint(sb, k_ColumnSeparator)
+		writeColumn(sb, right, f.language(), columnWidth)
+		fmt.Fprintln(sb)
+
+		if (left != nil && left.NoEOL()) || (right != nil && right.NoEOL()) {
+			// like String, the virtual line keeps the line map normalized.
+			commit.LineMap = append(commit.LineMap, node)
+			fmt.Fprint(sb, strings.Repeat(" ", prefixWidth))
+			writeNoEOLColumn(sb, left, columnWidth)
+			fmt.Fprint(sb, k_ColumnSeparator)
+			writeNoEOLColumn(sb, right, columnWidth)
+			fmt.Fprintln(sb)
+		}
+	})
+}
+
+// writeColumn writes the line with its styles in exactly width cells, truncating it if it doesn't
+// fit. A nil line leaves the column blank.
+func writeColumn(sb *strings.Builder, l *Line, syn syntax, width int) {
+	if l == nil {
+		fmt.Fprint(sb, strings.Repeat(" ", width))
+		return
+	}
+
+	runes := l.displayRunes(syn)
+	cells := cellWidth(runes)
+	if cells > width {
+		// the last cell marks that the line doesn't fit.
+		cells = 0
+		for i, r := range runes {
+			if cells+runewidth.RuneWidth(r.r) > width-1 {
+				r.r = k_Truncated
+				runes = append(runes[:i], r)
+				cells++
+				break
+			}
+			cells += runewidth.RuneWidth(r.r)
+		}
+	}
+	writeStyledRunes(sb, runes)
+	fmt.Fprint(sb, strings.Repeat(" ", width-cells))
+}
+
+// writeNoEOLColumn writes the marker for a line without an end of line, in width cells.
+func writeNoEOLColumn(sb *strings.Builder, l *Line, width int) {
+	if l == nil || !l.NoEOL() {
+		fmt.Fprint(sb, strings.Repeat(" ", width))
+		return
+	}
+
+	marker := []styledRune{}
+	for _, r := range l.Op.String() + strings.TrimSuffix(k_NoEOL, "\n") {
+		marker = append(marker, l.styled(r))
+	}
+	if len(marker) > width {
+		marker = marker[:width]
+	}
+	writeStyledRunes(sb, marker)
+	fmt.Fprint(sb, strings.Repeat(" ", width-len(marker)))
+}
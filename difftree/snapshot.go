@@ -0,0 +1,61 @@
+package difftree
+
+// SelectionSnapshot is the selection state of a commit at some point, for undoing changes to it. Only
+// the lines, and files without any chunks, are saved, as the state of chunks and files follows from
+// them.
+type SelectionSnapshot struct {
+	lines map[*Line]SelectionState
+	files map[*File]SelectionState
+}
+
+// SelectionSnapshot saves the selection state of the commit.
+func (commit *Commit) SelectionSnapshot() SelectionSnapshot {
+	s := SelectionSnapshot{lines: map[*Line]SelectionState{}, files: map[*File]SelectionState{}}
+	for _, f := range commit.Files {
+		if len(f.Chunks) == 0 {
+			s.files[f] = f.selection
+		}
+		for _, c := range f.Chunks {
+			for _, l := range c.Lines {
+				s.lines[l] = l.selection
+			}
+		}
+	}
+	return s
+}
+
+// RestoreSelection restores the selection state saved in the snapshot. Lines that didn't exist when
+// it was taken, like those from an edited chunk, keep their selection.
+func (commit *Commit) RestoreSelection(s SelectionSnapshot) {
+	for _, f := range commit.Files {
+		if state, ok := s.files[f]; ok {
+			f.selection = state
+		}
+		for _, c := range f.Chunks {
+			for _, l := range c.Lines {
+				if state, ok := s.lines[l]; ok {
+					l.selection = state
+				}
+			}
+			c.UpdateSelection()
+		}
+	}
+}
+
+// Equal returns whether the snapshots saved the same selection state.
+func (s SelectionSnapshot) Equal(other SelectionSnapshot) bool {
+	if len(s.lines) != len(other.lines) || len(s.files) != len(other.files) {
+		return false
+	}
+	for l, state := range s.lines {
+		if otherState, ok := other.lines[l]; !ok || otherState != state {
+			return false
+		}
+	}
+	for f, state := range s.files {
+		if otherState, ok := other.files[f]; !ok || otherState != state {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package difftree
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// Stats counts the files, chunks and changed lines of a commit, and how many of them are selected.
+// Partially selected files and chunks count as selected.
+type Stats struct {
+	Files, SelectedFiles   int
+	Chunks, SelectedChunks int
+	Lines, SelectedLines   int
+	// Adds and Deletes are the added and deleted lines of the whole commit.
+	Adds, Deletes int
+	// SelectedAdds and SelectedDeletes are the added and deleted lines in the selection.
+	SelectedAdds, SelectedDeletes int
+	// RemainingFiles are the files that aren't fully selected, which still have changes after
+	// committing the selection.
+	RemainingFiles int
+}
+
+// Stats counts the selection of every file, including those hidden by the filter, as they are
+// committed too.
+func (commit *Commit) Stats() Stats {
+	s := Stats{Files: len(commit.Files)}
+	for _, f := range commit.Files {
+		if f.selection != Deselected {
+			s.SelectedFiles++
+		}
+		if f.selection != Selected {
+			s.RemainingFiles++
+		}
+
+		s.Chunks += len(f.Chunks)
+		for _, c := range f.Chunks {
+			if c.selection != Deselected {
+				s.SelectedChunks++
+			}
+			for _, l := range c.Lines {
+				if l.Op == gitdiff.OpContext {
+					continue
+				}
+				s.Lines++
+				if l.Op == gitdiff.OpAdd {
+					s.Adds++
+				} else {
+					s.Deletes++
+				}
+				if l.selection != Selected {
+					continue
+				}
+				s.SelectedLines++
+				if l.Op == gitdiff.OpAdd {
+					s.SelectedAdds++
+				} else {
+					s.SelectedDeletes++
+				}
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,283 @@
+package difftree
+
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// syntax colours are shown on the backgrounds of deleted and added lines, so they avoid red and green,
+// which would be hard to tell apart from them.
+const (
+	k_KeywordColor = color.FgMagenta
+	k_StringColor  = color.FgYellow
+	k_NumberColor  = color.FgCyan
+	k_CommentColor = color.FgBlue
+)
+
+// syntaxSpan is a span of a line highlighted in a colour.
+type syntaxSpan struct {
+	span
+	color color.Attribute
+}
+
+// syntax highlights lines of a file one at a time. State that spans lines, like block comments, is
+// ignored, as a diff rarely shows enough of the file to know it anyway.
+type syntax interface {
+	highlight(line []rune) []syntaxSpan
+}
+
+// syntaxFor returns the syntax for the file name, or nil if it isn't a known language.
+func syntaxFor(name string) syntax {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".go":
+		return k_GoSyntax
+	case ".sh", ".bash", ".zsh":
+		return k_ShellSyntax
+	case ".yaml", ".yml":
+		return yamlSyntax{}
+	case ".md", ".markdown":
+		return markdownSyntax{}
+	}
+	return nil
+}
+
+// language returns the syntax of the file, or nil if it isn't a known language.
+func (f *File) language() syntax {
+	if f.IsDelete {
+		return syntaxFor(f.OldName)
+	}
+	return syntaxFor(f.NewName)
+}
+
+// codeSyntax highlights C-like programming languages.
+type codeSyntax struct {
+	keywords       map[string]bool
+	lineComments   []string
+	blockComment   [2]string
+	quotes         string
+	variablePrefix rune
+}
+
+var k_GoSyntax = &codeSyntax{
+	keywords: wordSet(`break case chan const continue default defer else fallthrough for func go goto
+		if import interface map package range return select struct switch type var
+		true false nil iota`),
+	lineComments: []string{"//"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes:       "\"'`",
+}
+
+var k_ShellSyntax = &codeSyntax{
+	keywords: wordSet(`if then else elif fi for while until do done case esac function in select
+		return local export readonly declare set unset shift exit`),
+	lineComments:   []string{"#"},
+	quotes:         "\"'",
+	variablePrefix: '$',
+}
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// hasPrefixAt returns whether line has the prefix at index i. The runes are compared in place, as
+// this is called at every index of every line shown.
+func hasPrefixAt(line []rune, i int, prefix string) bool {
+	if len(prefix) == 0 {
+		return false
+	}
+	for _, r := range prefix {
+		if i >= len(line) || line[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (s *codeSyntax) highlight(line []rune) []syntaxSpan {
+	spans := []syntaxSpan{}
+	for i := 0; i < len(line); {
+		start := i
+		r := line[i]
+		switch {
+		case s.isLineComment(line, i):
+			return append(spans, syntaxSpan{span{i, len(line)}, k_CommentColor})
+		case hasPrefixAt(line, i, s.blockComment[0]):
+			i += len(s.blockComment[0])
+			for i < len(line) && !hasPrefixAt(line, i, s.blockComment[1]) {
+				i++
+			}
+			if i < len(line) {
+				i += len(s.blockComment[1])
+			}
+			spans = append(spans, syntaxSpan{span{start, i}, k_CommentColor})
+		case strings.ContainsRune(s.quotes, r):
+			i++
+			for i < len(line) && line[i] != r {
+				if line[i] == '\\' && r != '`' {
+					i++
+				}
+				i++
+			}
+			// a backslash at the end of the line escapes nothing.
+			if i > len(line) {
+				i = len(line)
+			} else if i < len(line) {
+				i++
+			}
+			spans = append(spans, syntaxSpan{span{start, i}, k_StringColor})
+		case r == s.variablePrefix && s.variablePrefix != 0:
+			i++
+			if i < len(line) && line[i] == '{' {
+				for i < len(line) && line[i] != '}' {
+					i++
+				}
+				if i < len(line) {
+					i++
+				}
+			} else {
+				for i < len(line) && isWordRune(line[i]) {
+					i++
+				}
+			}
+			spans = append(spans, syntaxSpan{span{start, i}, k_NumberColor})
+		case unicode.IsDigit(r):
+			for i < len(line) && (isWordRune(line[i]) || line[i] == '.') {
+				i++
+			}
+			spans = append(spans, syntaxSpan{span{start, i}, k_NumberColor})
+		case isWordRune(r):
+			for i < len(line) && isWordRune(line[i]) {
+				i++
+			}
+			if s.keywords[string(line[start:i])] {
+				spans = append(spans, syntaxSpan{span{start, i}, k_KeywordColor})
+			}
+		default:
+			i++
+		}
+	}
+	return spans
+}
+
+func (s *codeSyntax) isLineComment(line []rune, i int) bool {
+	for _, prefix := range s.lineComments {
+		// a shell comment has to start a word, e.g. ${#array} isn't one.
+		if hasPrefixAt(line, i, prefix) && (prefix != "#" || i == 0 || unicode.IsSpace(line[i-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlSyntax highlights mapping keys, comments, quoted strings and scalar numbers and booleans.
+type yamlSyntax struct{}
+
+func (yamlSyntax) highlight(line []rune) []syntaxSpan {
+	spans := []syntaxSpan{}
+	i := 0
+	for i < len(line) && (unicode.IsSpace(line[i]) || line[i] == '-') {
+		i++
+	}
+
+	// a key is everything up to the first ": " (or a ':' at the end of the line).
+	if key := strings.Index(string(line[i:]), ":"); key > 0 && !strings.HasPrefix(string(line[i:]), "#") {
+		keyEnd := i + len([]rune(string(line[i:])[:key]))
+		if keyEnd+1 == len(line) || unicode.IsSpace(line[keyEnd+1]) {
+			spans = append(spans, syntaxSpan{span{i, keyEnd}, k_KeywordColor})
+			i = keyEnd + 1
+		}
+	}
+
+	for i < len(line) {
+		start := i
+		switch r := line[i]; {
+		case r == '#' && (i == 0 || unicode.IsSpace(line[i-1])):
+			return append(spans, syntaxSpan{span{i, len(line)}, k_CommentColor})
+		case r == '"' || r == '\'':
+			i++
+			for i < len(line) && line[i] != r {
+				if line[i] == '\\' && r == '"' {
+					i++
+				}
+				i++
+			}
+			// a backslash at the end of the line escapes nothing.
+			if i > len(line) {
+				i = len(line)
+			} else if i < len(line) {
+				i++
+			}
+			spans = append(spans, syntaxSpan{span{start, i}, k_StringColor})
+		case isWordRune(r):
+			for i < len(line) && (isWordRune(line[i]) || line[i] == '.') {
+				i++
+			}
+			switch word := string(line[start:i]); {
+			case unicode.IsDigit(r), word == "true", word == "false", word == "null", word == "~":
+				spans = append(spans, syntaxSpan{span{start, i}, k_NumberColor})
+			}
+		default:
+			i++
+		}
+	}
+	return spans
+}
+
+// markdownSyntax highlights headings, list markers, quotes, code spans and link targets.
+type markdownSyntax struct{}
+
+func (markdownSyntax) highlight(line []rune) []syntaxSpan {
+	trimmed := strings.TrimLeftFunc(string(line), unicode.IsSpace)
+	indent := len(line) - len([]rune(trimmed))
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		return []syntaxSpan{{span{indent, len(line)}, k_KeywordColor}}
+	case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+		return []syntaxSpan{{span{indent, len(line)}, k_StringColor}}
+	}
+
+	spans := []syntaxSpan{}
+	i := indent
+	for _, marker := range []string{"* ", "- ", "+ ", "> "} {
+		if strings.HasPrefix(trimmed, marker) {
+			spans = append(spans, syntaxSpan{span{i, i + 1}, k_NumberColor})
+			i++
+			break
+		}
+	}
+	for i < len(line) {
+		start := i
+		switch {
+		case line[i] == '`':
+			i++
+			for i < len(line) && line[i] != '`' {
+				i++
+			}
+			if i < len(line) {
+				i++
+			}
+			spans = append(spans, syntaxSpan{span{start, i}, k_StringColor})
+		case hasPrefixAt(line, i, "]("):
+			i += 2
+			for i < len(line) && line[i] != ')' {
+				i++
+			}
+			spans = append(spans, syntaxSpan{span{start + 2, i}, k_CommentColor})
+		default:
+			i++
+		}
+	}
+	return spans
+}
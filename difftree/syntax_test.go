@@ -0,0 +1,27 @@
+package difftree
+
+import (
+	"testing"
+)
+
+func TestHighlightTrailingBackslash(t *testing.T) {
+	tests := []struct {
+		name, line string
+	}{
+		{"script.sh", `echo "foo \`},
+		{"config.yaml", `k: "foo \`},
+		{"main.go", `x := 'a\`},
+	}
+	for _, test := range tests {
+		line := []rune(test.line)
+		spans := syntaxFor(test.name).highlight(line)
+		if len(spans) == 0 {
+			t.Errorf("%s: %q: no spans", test.name, test.line)
+		}
+		for _, s := range spans {
+			if s.start < 0 || s.end > len(line) || s.start > s.end {
+				t.Errorf("%s: %q: span %d-%d is outside the line of %d runes", test.name, test.line, s.start, s.end, len(line))
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package difftree
+
+import (
+	"unicode"
+)
+
+// k_MaxWordDiffCells limits the size of the table used to compare the words of two lines. Longer
+// lines are only compared by their common prefix and suffix.
+const k_MaxWordDiffCells = 1 << 20
+
+// span is a range of runes in a line, from start up to but not including end.
+type span struct {
+	start, end int
+}
+
+// splitWords splits a line into words, runs of whitespace and single punctuation characters.
+func splitWords(s []rune) []span {
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	words := make([]span, 0, len(s)/2)
+	for start := 0; start < len(s); {
+		end := start + 1
+		switch {
+		case isWord(s[start]):
+			for end < len(s) && isWord(s[end]) {
+				end++
+			}
+		case unicode.IsSpace(s[start]):
+			for end < len(s) && unicode.IsSpace(s[end]) {
+				end++
+			}
+		}
+		words = append(words, span{start, end})
+		start = end
+	}
+	return words
+}
+
+// wordDiff returns the spans of a and b that aren't in the longest common subsequence of their
+// words, like `git diff --word-diff`. Whitespace between two changed words is included in the
+// change so that it is highlighted as one.
+func wordDiff(a, b []rune) (aChanges, bChanges []span) {
+	aWords, bWords := splitWords(a), splitWords(b)
+	if (len(aWords)+1)*(len(bWords)+1) > k_MaxWordDiffCells {
+		aStart, aEnd := changedRange(a, b)
+		bStart, bEnd := changedRange(b, a)
+		return []span{{aStart, aEnd}}, []span{{bStart, bEnd}}
+	}
+
+	equal := func(i, j int) bool {
+		return string(a[aWords[i].start:aWords[i].end]) == string(b[bWords[j].start:bWords[j].end])
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of aWords[i:] and bWords[j:].
+	lengths := make([][]int, len(aWords)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(bWords)+1)
+	}
+	for i := len(aWords) - 1; i >= 0; i-- {
+		for j := len(bWords) - 1; j >= 0; j-- {
+			if equal(i, j) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	aChanged := make([]bool, len(aWords))
+	bChanged := make([]bool, len(bWords))
+	i, j := 0, 0
+	for i < len(aWords) && j < len(bWords) {
+		if equal(i, j) {
+			i++
+			j++
+		} else if lengths[i+1][j] >= lengths[i][j+1] {
+			aChanged[i] = true
+			i++
+		} else {
+			bChanged[j] = true
+			j++
+		}
+	}
+	for ; i < len(aWords); i++ {
+		aChanged[i] = true
+	}
+	for ; j < len(bWords); j++ {
+		bChanged[j] = true
+	}
+
+	return changedSpans(a, aWords, aChanged), changedSpans(b, bWords, bChanged)
+}
+
+// changedSpans merges the changed words of s into spans.
+func changedSpans(s []rune, words []span, changed []bool) []span {
+	spans := []span{}
+	for i, word := range words {
+		joinsChanges := i > 0 && i+1 < len(words) && changed[i-1] && changed[i+1] &&
+			unicode.IsSpace(s[word.start])
+		if !changed[i] && !joinsChanges {
+			continue
+		}
+
+		if len(spans) > 0 && spans[len(spans)-1].end == word.start {
+			spans[len(spans)-1].end = word.end
+		} else {
+			spans = append(spans, word)
+		}
+	}
+	return spans
+}
+
+// changedRange returns the range of a that differs from b, after their common prefix and suffix.
+func changedRange(a, b []rune) (start, end int) {
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	end = len(a)
+	for bEnd := len(b); end > start && bEnd > start && a[end-1] == b[bEnd-1]; bEnd-- {
+		end--
+	}
+	return start, end
+}
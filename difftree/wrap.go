@@ -0,0 +1,52 @@
+package difftree
+
+import (
+	"github.com/mattn/go-runewidth"
+)
+
+// k_TabWidth is how many cells apart tab stops are. Tabs are expanded before gocui sees them, as it
+// expands them to a fixed 4 cells wherever they are.
+const k_TabWidth = 4
+
+// expandTabs replaces each tab with spaces up to the next tab stop, counting from the first rune.
+func expandTabs(runes []styledRune) []styledRune {
+	expanded := make([]styledRune, 0, len(runes))
+	cells := 0
+	for _, r := range runes {
+		if r.r != '\t' {
+			expanded = append(expanded, r)
+			cells += runewidth.RuneWidth(r.r)
+			continue
+		}
+		r.r = ' '
+		for n := k_TabWidth - cells%k_TabWidth; n > 0; n-- {
+			expanded = append(expanded, r)
+			cells++
+		}
+	}
+	return expanded
+}
+
+// cellWidth returns how many cells the runes take up on screen, as wide runes take up two.
+func cellWidth(runes []styledRune) int {
+	cells := 0
+	for _, r := range runes {
+		cells += runewidth.RuneWidth(r.r)
+	}
+	return cells
+}
+
+// wrapRunes splits the runes into rows of at most width cells, though a row has at least one rune.
+func wrapRunes(runes []styledRune, width int) [][]styledRune {
+	rows := [][]styledRune{}
+	start, cells := 0, 0
+	for i, r := range runes {
+		w := runewidth.RuneWidth(r.r)
+		if cells+w > width && i > start {
+			rows = append(rows, runes[start:i])
+			start, cells = i, 0
+		}
+		cells += w
+	}
+	return append(rows, runes[start:])
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+// editInEditor opens s in the user's Git editor (core.editor, $GIT_EDITOR, $VISUAL or $EDITOR) and
+// returns the edited text. The GUI is suspended while the editor has the terminal.
+func editInEditor(g *gocui.Gui, s string) (string, error) {
+	editor, err := git.GitOutput("var", "GIT_EDITOR")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "git-split*.diff")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(s); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	gocui.Suspend()
+	// like Git, we let the shell handle any arguments included in the editor setting.
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, f.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if resumeErr := gocui.Resume(); resumeErr != nil {
+		return "", resumeErr
+	}
+	setMouseModes(g)
+	if err != nil {
+		return "", err
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	return string(edited), err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/mattn/go-runewidth"
+	ir "github.com/smithjacobj/git-split/difftree"
+)
+
+const k_FilterView = "filter"
+
+// FilterView shows the file filter and how many files it hides, over the right of the help bar.
+type FilterView struct {
+	*gocui.Gui
+	*gocui.View
+}
+
+// LayoutFilterView shows the view if the commit is filtered, and removes it otherwise, in which case
+// the returned view is nil.
+func LayoutFilterView(g *gocui.Gui, c *ir.Commit) (v *FilterView, err error) {
+	if len(c.Filter) == 0 {
+		if err := g.DeleteView(k_FilterView); err != nil && err != gocui.ErrUnknownView {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	v = &FilterView{Gui: g}
+	text := fmt.Sprintf("filter: %s (%d hidden)", c.Filter, c.HiddenFileCount())
+	maxX, _ := g.Size()
+	x0 := maxX - runewidth.StringWidth(text) - 3
+	if x0 < 0 {
+		x0 = 0
+	}
+	if v.View, err = g.SetView(k_FilterView, x0, 0, maxX-1, k_HelpViewHeight-1, 0); err != nil && err != gocui.ErrUnknownView {
+		return nil, err
+	}
+	v.View.FgColor = gocui.ColorYellow
+	v.View.Clear()
+	fmt.Fprint(v.View, text)
+	return v, nil
+}
+
+// startFilter opens a prompt for a file filter, which narrows the files as it is typed. A filter
+// that would hide every file isn't applied, and escape restores the previous filter.
+func startFilter(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		_, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+		previous := v.commit.Filter
+		applied := previous
+
+		apply := func(pattern string) {
+			if v.commit.SetFilter(pattern) == len(v.commit.Files) {
+				v.commit.SetFilter(applied)
+			} else {
+				applied = pattern
+			}
+
+			v.printContent()
+			if y := v.lineNumberOf(node); y >= 0 {
+				v.View.SetCursor(0, y)
+			} else {
+				v.View.SetCursor(0, 0)
+			}
+			fixScroll(v.View)
+		}
+
+		return OpenPromptView(g, "filter: ", previous,
+			func(input string) error {
+				apply(input)
+				return nil
+			},
+			func(input string, ok bool) error {
+				if !ok {
+					apply(previous)
+				} else if input != applied {
+					return ShowMessage(g, fmt.Sprintf("No files match: %s", input))
+				}
+				return nil
+			},
+		)
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/awesome-gocui/gocui v1.1.1-0.20220726193820-384f06fbdddf
 	github.com/bluekeyes/go-gitdiff v0.7.0
 	github.com/fatih/color v1.13.0
+	github.com/mattn/go-runewidth v0.0.14
 	github.com/smithjacobj/go-git-utils v0.0.0-20221104065301-277ae4622746
 )
 
@@ -15,7 +16,6 @@ require (
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.9 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
-	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/rivo/uniseg v0.4.2 // indirect
 	golang.org/x/sys v0.0.0-20221010170243-090e33056c14 // indirect
 	golang.org/x/term v0.0.0-20220919170432-7a66f970e087 // indirect
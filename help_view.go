@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/awesome-gocui/gocui"
 	"github.com/fatih/color"
@@ -31,14 +32,24 @@ func LayoutHelpView(g *gocui.Gui) (v *HelpView, err error) {
 	return v, nil
 }
 
+// printContent prints the actions of k_HelpEntries with the first key bound to each, skipping those
+// without any keys.
 func (v *HelpView) printContent() {
-	v.printKeybind("space", "toggle selection")
-	v.printKeybind("a", "select all")
-	v.printKeybind("A", "select none")
-	v.printKeybind("q", "abort")
-	v.printKeybind("c", "confirm")
-	v.printKeybind("up/down", "navigate")
-	v.printKeybind("left/right", "collapse/expand")
+	for _, entry := range k_HelpEntries {
+		keys := []string{}
+		for _, name := range entry.actions {
+			actionKeys := findKeyAction(name).keys
+			if name == "help" {
+				actionKeys = helpKeys()
+			}
+			if len(actionKeys) > 0 {
+				keys = append(keys, actionKeys[0])
+			}
+		}
+		if len(keys) > 0 {
+			v.printKeybind(strings.Join(keys, "/"), entry.usage)
+		}
+	}
 }
 
 func (v *HelpView) printKeybind(key, usage string) {
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awesome-gocui/gocui"
+	ir "github.com/smithjacobj/git-split/difftree"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+const k_KeysConfigSection = "split.keys."
+const k_KeysPreset = "preset"
+
+// keyAction is something that keys can be bound to, like moving the cursor.
+type keyAction struct {
+	name    string
+	keys    []string
+	usage   string
+	handler func(v *MainView) func(*gocui.Gui, *gocui.View) error
+	// global actions are bound in every view, so that keys that aren't characters also work while
+	// typing in a prompt.
+	global bool
+}
+
+// g_KeyActions are the actions and the keys bound to them, which start as the defaults and are
+// changed by loadKeymap. They are set in init, as the help handler refers to them.
+var g_KeyActions []*keyAction
+
+func init() {
+	g_KeyActions = []*keyAction{
+		{name: "up", usage: "move up", keys: []string{"up"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return moveCursor(v, -1) }},
+		{name: "page-up", usage: "move up 15 lines", keys: []string{"shift-up", "pgup"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return moveCursor(v, -15) }},
+		{name: "down", usage: "move down", keys: []string{"down"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return moveCursor(v, 1) }},
+		{name: "page-down", usage: "move down 15 lines", keys: []string{"shift-down", "pgdn"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return moveCursor(v, 15) }},
+		{name: "collapse", usage: "collapse file/chunk", keys: []string{"left"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return setExpansionState(v, ir.Collapsed) }},
+		{name: "collapse-all", usage: "collapse all", keys: []string{"shift-left"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return setExpansionAll(v, ir.Collapsed) }},
+		{name: "expand", usage: "expand file/chunk", keys: []string{"right"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return setExpansionState(v, ir.Expanded) }},
+		{name: "expand-all", usage: "expand all", keys: []string{"shift-right"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return setExpansionAll(v, ir.Expanded) }},
+		{name: "toggle", usage: "toggle selection", keys: []string{"space"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return toggleSelection(v, false) }},
+		{name: "toggle-single", usage: "toggle single line, even in a modified pair", keys: []string{"x"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return toggleSelection(v, true) }},
+		{name: "split", usage: "split chunk", keys: []string{"s"}, handler: splitChunk},
+		{name: "edit", usage: "edit chunk in your editor", keys: []string{"e"}, handler: editChunk},
+		{name: "side-by-side", usage: "toggle side-by-side layout", keys: []string{"t"}, handler: toggleSideBySide},
+		{name: "more-context", usage: "show more context from HEAD", keys: []string{"m"}, handler: showMoreContext},
+		{name: "diff-algorithm", usage: "change the diff algorithm", keys: []string{"D"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return rediff(v, cycleDiffAlgorithm) }},
+		{name: "diff-context-less", usage: "diff with fewer context lines", keys: []string{"-"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return rediff(v, changeDiffContext(-1)) }},
+		{name: "diff-context-more", usage: "diff with more context lines", keys: []string{"+"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return rediff(v, changeDiffContext(1)) }},
+		{name: "ignore-whitespace", usage: "toggle ignoring whitespace in the diff", keys: []string{"W"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return rediff(v, toggleIgnoreWhitespace) }},
+		{name: "wrap", usage: "toggle wrapping long lines", keys: []string{"w"}, handler: toggleWrap},
+		{name: "parts", usage: "show or hide the parts committed so far", keys: []string{"p"}, handler: toggleParts},
+		{name: "scroll-left", usage: "scroll left", keys: []string{"<"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return scrollHorizontal(v, -1) }},
+		{name: "scroll-right", usage: "scroll right", keys: []string{">"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return scrollHorizontal(v, 1) }},
+		{name: "search", usage: "search forward", keys: []string{"/"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return startSearch(v, false) }},
+		{name: "search-backward", usage: "search backward", keys: []string{"?"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return startSearch(v, true) }},
+		{name: "search-next", usage: "go to next match", keys: []string{"n"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return searchNext(v, false) }},
+		{name: "search-previous", usage: "go to previous match", keys: []string{"N"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return searchNext(v, true) }},
+		{name: "command", usage: "run a command", keys: []string{":"}, handler: startCommand},
+		{name: "undo", usage: "undo selection change", keys: []string{"u"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return undo(v, false) }},
+		{name: "redo", usage: "redo selection change", keys: []string{"ctrl-r"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return undo(v, true) }},
+		{name: "visual", usage: "start/stop visual range", keys: []string{"v"}, handler: toggleVisual},
+		{name: "visual-exit", usage: "leave visual range", keys: []string{"esc"}, handler: exitVisual},
+		{name: "filter", usage: "filter files", keys: []string{"f"}, handler: startFilter},
+		{name: "confirm", usage: "confirm and commit the selection", keys: []string{"c"}, handler: confirm},
+		{name: "select-all", usage: "select all", keys: []string{"a"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return selectAll(v, ir.Selected) }},
+		{name: "select-none", usage: "select none", keys: []string{"A"}, handler: func(v *MainView) func(*gocui.Gui, *gocui.View) error { return selectAll(v, ir.Deselected) }},
+		{name: "help", usage: "show all keys", keys: []string{}, handler: func(*MainView) func(*gocui.Gui, *gocui.View) error { return OpenKeysView }},
+		{name: "quit", usage: "abort splitting", keys: []string{"q", "ctrl-c"}, handler: func(*MainView) func(*gocui.Gui, *gocui.View) error { return quit }, global: true},
+	}
+}
+
+// k_KeyPresets are sets of keys added in front of the defaults, so that they are shown in the help.
+var k_KeyPresets = map[string]map[string][]string{
+	"vim": {
+		"up":        {"k"},
+		"down":      {"j"},
+		"page-up":   {"ctrl-u"},
+		"page-down": {"ctrl-d"},
+		"collapse":  {"h"},
+		"expand":    {"l"},
+	},
+}
+
+// k_HelpEntries are the actions shown in the help bar, with the first key of each.
+var k_HelpEntries = []struct {
+	actions []string
+	usage   string
+}{
+	{[]string{"help"}, "all keys"},
+	{[]string{"toggle"}, "toggle selection"},
+	{[]string{"toggle-single"}, "toggle single line"},
+	{[]string{"visual"}, "visual range"},
+	{[]string{"select-all"}, "select all"},
+	{[]string{"select-none"}, "select none"},
+	{[]string{"undo", "redo"}, "undo/redo"},
+	{[]string{"split"}, "split chunk"},
+	{[]string{"edit"}, "edit chunk"},
+	{[]string{"side-by-side"}, "side-by-side"},
+	{[]string{"wrap"}, "wrap"},
+	{[]string{"search"}, "search"},
+	{[]string{"command"}, "command"},
+	{[]string{"filter"}, "filter files"},
+	{[]string{"quit"}, "abort"},
+	{[]string{"confirm"}, "confirm"},
+	{[]string{"up", "down"}, "navigate"},
+	{[]string{"collapse", "expand"}, "collapse/expand"},
+}
+
+// k_KeyNames are the names of keys that aren't characters. With "shift-" in front, they are
+// shifted.
+var k_KeyNames = map[string]gocui.Key{
+	"up":     gocui.KeyArrowUp,
+	"down":   gocui.KeyArrowDown,
+	"left":   gocui.KeyArrowLeft,
+	"right":  gocui.KeyArrowRight,
+	"pgup":   gocui.KeyPgup,
+	"pgdn":   gocui.KeyPgdn,
+	"home":   gocui.KeyHome,
+	"end":    gocui.KeyEnd,
+	"space":  gocui.KeySpace,
+	"enter":  gocui.KeyEnter,
+	"esc":    gocui.KeyEsc,
+	"tab":    gocui.KeyTab,
+	"delete": gocui.KeyDelete,
+}
+
+// parseKey parses a key name: a character, one of k_KeyNames, "ctrl-" and a letter, "alt-" and a
+// character, or "shift-" and one of k_KeyNames.
+func parseKey(name string) (key interface{}, mod gocui.Modifier, err error) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return r, gocui.ModNone, nil
+	} else if key, ok := k_KeyNames[name]; ok {
+		return key, gocui.ModNone, nil
+	} else if rest := strings.TrimPrefix(name, "shift-"); rest != name {
+		if key, ok := k_KeyNames[rest]; ok {
+			return key, gocui.ModShift, nil
+		}
+	} else if rest := strings.TrimPrefix(name, "ctrl-"); rest != name && len(rest) == 1 && rest[0] >= 'a' && rest[0] <= 'z' {
+		return gocui.KeyCtrlA + gocui.Key(rest[0]-'a'), gocui.ModNone, nil
+	} else if rest := strings.TrimPrefix(name, "alt-"); rest != name && utf8.RuneCountInString(rest) == 1 {
+		r, _ := utf8.DecodeRuneInString(rest)
+		return r, gocui.ModAlt, nil
+	}
+	return nil, gocui.ModNone, fmt.Errorf("unknown key %q", name)
+}
+
+// k_HelpSearch is typed at the start of a backward search to show all keys instead, so that the help
+// is on ? as well as the backward search. The help action has no keys by default for this reason.
+const k_HelpSearch = "?"
+
+// helpKeys returns the keys that show all keys: those of the help action, or if it has none, the
+// first key of the backward search followed by k_HelpSearch.
+func helpKeys() []string {
+	if keys := findKeyAction("help").keys; len(keys) > 0 {
+		return keys
+	} else if keys := findKeyAction("search-backward").keys; len(keys) > 0 {
+		return []string{keys[0] + k_HelpSearch}
+	}
+	return nil
+}
+
+func findKeyAction(name string) *keyAction {
+	for _, a := range g_KeyActions {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// loadKeymap changes the keys of g_KeyActions from the keys config file and then the git config,
+// which takes precedence. Both set "<action> = <keys>" with the keys separated by spaces, or a preset
+// of keys to add to the defaults with "preset = vim". A key bound to an action is unbound from any
+// other action.
+func loadKeymap() error {
+	settings, sources, err := readKeysConfigFile()
+	if err != nil {
+		return err
+	}
+	cmd := git.GitCmd("config", "--get-regexp", `^`+strings.ReplaceAll(k_KeysConfigSection, ".", `\.`))
+	rawOutput, err := cmd.Output()
+	// git config exits with 1 if no keys are set.
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		rawOutput, err = nil, nil
+	}
+	output, err := cmd.FormatOutput(rawOutput, err)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		name, value, _ := strings.Cut(line, " ")
+		if name = strings.TrimPrefix(name, k_KeysConfigSection); len(name) > 0 {
+			settings[name] = value
+			sources[name] = "git config"
+		}
+	}
+
+	if preset, ok := settings[k_KeysPreset]; ok {
+		keys, ok := k_KeyPresets[preset]
+		if !ok {
+			return fmt.Errorf("%s: unknown keys preset %q", sources[k_KeysPreset], preset)
+		}
+		for name, presetKeys := range keys {
+			a := findKeyAction(name)
+			a.keys = append(append([]string{}, presetKeys...), a.keys...)
+		}
+		delete(settings, k_KeysPreset)
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if findKeyAction(name) == nil {
+			return fmt.Errorf("%s: unknown action %q", sources[name], name)
+		}
+	}
+
+	// the settings are applied in the order of the actions, so that the result doesn't depend on the
+	// order of the map, and a key can't be set for two of them.
+	boundBy := map[string]string{}
+	for _, a := range g_KeyActions {
+		value, ok := settings[a.name]
+		if !ok {
+			continue
+		}
+		keys := strings.Fields(value)
+		for _, key := range keys {
+			if _, _, err := parseKey(key); err != nil {
+				return fmt.Errorf("%s: %s: %w", sources[a.name], a.name, err)
+			}
+			if other, ok := boundBy[key]; ok && other != a.name {
+				return fmt.Errorf("%s: %s: key %q is also set for %s", sources[a.name], a.name, key, other)
+			}
+			boundBy[key] = a.name
+			for _, other := range g_KeyActions {
+				other.keys = removeKey(other.keys, key)
+			}
+		}
+		a.keys = keys
+	}
+	return nil
+}
+
+func removeKey(keys []string, key string) []string {
+	result := keys[:0]
+	for _, k := range keys {
+		if k != key {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// readKeysConfigFile reads the settings from $XDG_CONFIG_HOME/git-split/keys, or
+// ~/.config/git-split/keys, if it exists. Blank lines and those starting with # are ignored. It also
+// returns where each setting came from.
+func readKeysConfigFile() (settings map[string]string, sources map[string]string, err error) {
+	settings, sources = map[string]string{}, map[string]string{}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return settings, sources, nil
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	path := filepath.Join(dir, "git-split", "keys")
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return settings, sources, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("%s:%d: expected <action> = <keys>", path, lineNumber)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		settings[name] = strings.TrimSpace(value)
+		sources[name] = fmt.Sprintf("%s:%d", path, lineNumber)
+	}
+	return settings, sources, scanner.Err()
+}
+
+// bindKeys binds the keys of the actions to their handlers for the main view, or globally for the
+// global actions if v is nil.
+func bindKeys(g *gocui.Gui, v *MainView) error {
+	for _, a := range g_KeyActions {
+		if a.global != (v == nil) {
+			continue
+		}
+		viewName := ""
+		if v != nil {
+			viewName = v.View.Name()
+		}
+
+		for _, name := range a.keys {
+			key, mod, err := parseKey(name)
+			if err != nil {
+				return err
+			}
+			if err := g.SetKeybinding(viewName, key, mod, a.handler(v)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+)
+
+const k_KeysView = "keys"
+const k_KeysViewMargin = 2
+
+// k_MouseUsage lists the mouse bindings, which can't be changed, after the keys.
+var k_MouseUsage = [][2]string{
+	{"click", "move to line, toggle checkbox, or expand/collapse (+)/(-)"},
+	{"wheel", "scroll"},
+}
+
+// KeysView lists every action with the keys bound to it, and the keys of the other views, over the
+// middle of the screen. It scrolls with the keys that move the cursor and the mouse wheel, and is
+// closed with escape, q, ? or the keys that opened it.
+type KeysView struct {
+	*gocui.Gui
+	*gocui.View
+
+	previousView string
+	lineCount    int
+}
+
+// OpenKeysView opens the list of keys and makes it the current view until it is closed.
+func OpenKeysView(g *gocui.Gui, _ *gocui.View) error {
+	v := &KeysView{Gui: g}
+	if current := g.CurrentView(); current != nil {
+		v.previousView = current.Name()
+	}
+
+	lines, width := keysViewLines()
+	v.lineCount = len(lines)
+	x0, y0, x1, y1 := keysViewBounds(g, width, len(lines))
+	var err error
+	if v.View, err = g.SetView(k_KeysView, x0, y0, x1, y1, 0); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	v.View.Title = "Keys"
+	v.View.Subtitle = "esc to close"
+	v.View.Clear()
+	v.View.WriteString(strings.Join(lines, "\n"))
+
+	if err := v.setKeybindings(); err != nil {
+		return err
+	}
+	_, err = g.SetCurrentView(k_KeysView)
+	return err
+}
+
+// LayoutKeysView keeps the list of keys in the middle of the screen, if it is shown.
+func LayoutKeysView(g *gocui.Gui) error {
+	if _, err := g.View(k_KeysView); err != nil {
+		return nil
+	}
+	lines, width := keysViewLines()
+	x0, y0, x1, y1 := keysViewBounds(g, width, len(lines))
+	_, err := g.SetView(k_KeysView, x0, y0, x1, y1, 0)
+	return err
+}
+
+// keysViewBounds centers the list of keys on the screen, shrinking it to fit.
+func keysViewBounds(g *gocui.Gui, width, height int) (x0, y0, x1, y1 int) {
+	// the frame takes a column and row on each side.
+	width, height = width+1, height+1
+	maxX, maxY := g.Size()
+	if maxWidth := maxX - 1 - 2*k_KeysViewMargin; width > maxWidth {
+		width = maxWidth
+	}
+	if maxHeight := maxY - 1 - 2*k_KeysViewMargin; height > maxHeight {
+		height = maxHeight
+	}
+	x0, y0 = (maxX-width)/2, (maxY-height)/2
+	return x0, y0, x0 + width, y0 + height
+}
+
+// keysSection is a titled list of keys and what they do in the list of keys.
+type keysSection struct {
+	title string
+	keys  [][2]string
+}
+
+// keysSections returns the keys of the main view and the mouse, then those of the other views,
+// from the tables they are bound from.
+func keysSections() []keysSection {
+	splitting := keysSection{title: "Splitting"}
+	for _, a := range g_KeyActions {
+		keys := a.keys
+		if a.name == "help" {
+			keys = helpKeys()
+		}
+		if len(keys) > 0 {
+			splitting.keys = append(splitting.keys, [2]string{strings.Join(keys, ", "), a.usage})
+		}
+	}
+	splitting.keys = append(splitting.keys, k_MouseUsage...)
+
+	prompt := keysSection{title: "Search, filter and command prompts"}
+	for _, b := range k_PromptKeys {
+		prompt.keys = append(prompt.keys, [2]string{strings.Join(b.keys, ", "), b.usage})
+	}
+
+	continueDialog := keysSection{title: "After a part is committed"}
+	for _, choice := range k_ContinueChoices {
+		continueDialog.keys = append(continueDialog.keys, [2]string{string(choice.key), choice.description})
+	}
+	for _, b := range k_ContinueKeys {
+		continueDialog.keys = append(continueDialog.keys, [2]string{strings.Join(b.keys, ", "), b.usage})
+	}
+
+	summary := keysSection{title: "Summary of the parts"}
+	for _, b := range k_SummaryKeys {
+		summary.keys = append(summary.keys, [2]string{strings.Join(b.keys, ", "), b.usage})
+	}
+	summary.keys = append(summary.keys, [2]string{strings.Join(findKeyAction("quit").keys, ", "), "abort splitting"})
+
+	return []keysSection{splitting, prompt, continueDialog, summary}
+}
+
+// keysViewLines returns a line for the title of each section of keys and each key in it, with a blank
+// line between sections, and the width of the longest line.
+func keysViewLines() (lines []string, width int) {
+	sections := keysSections()
+	keyWidth := 0
+	for _, s := range sections {
+		for _, k := range s.keys {
+			if w := runewidth.StringWidth(k[0]); w > keyWidth {
+				keyWidth = w
+			}
+		}
+	}
+
+	for i, s := range sections {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, " "+color.YellowString(s.title))
+		if w := runewidth.StringWidth(s.title) + 2; w > width {
+			width = w
+		}
+		for _, k := range s.keys {
+			padding := strings.Repeat(" ", keyWidth-runewidth.StringWidth(k[0]))
+			lines = append(lines, fmt.Sprintf(" %s%s  %s ", color.CyanString(k[0]), padding, k[1]))
+			if w := keyWidth + runewidth.StringWidth(k[1]) + 4; w > width {
+				width = w
+			}
+		}
+	}
+	return lines, width
+}
+
+func (v *KeysView) setKeybindings() error {
+	scrolls := map[string]int{"up": -1, "down": 1, "page-up": -15, "page-down": 15}
+	for name, dy := range scrolls {
+		for _, key := range findKeyAction(name).keys {
+			if err := v.bindKey(key, v.scroll(dy)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range append([]string{"esc", "q", k_HelpSearch}, findKeyAction("help").keys...) {
+		if err := v.bindKey(key, v.close); err != nil {
+			return err
+		}
+	}
+	if err := v.Gui.SetKeybinding(k_KeysView, gocui.MouseWheelUp, gocui.ModNone, v.scroll(-k_WheelLines)); err != nil {
+		return err
+	}
+	return v.Gui.SetKeybinding(k_KeysView, gocui.MouseWheelDown, gocui.ModNone, v.scroll(k_WheelLines))
+}
+
+func (v *KeysView) bindKey(name string, handler func(*gocui.Gui, *gocui.View) error) error {
+	key, mod, err := parseKey(name)
+	if err != nil {
+		return err
+	}
+	return v.Gui.SetKeybinding(k_KeysView, key, mod, handler)
+}
+
+func (v *KeysView) scroll(dy int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		_, height := v.View.Size()
+		_, oy := v.View.Origin()
+		newOy := oy + dy
+		if maxOy := v.lineCount - height; newOy > maxOy {
+			newOy = maxOy
+		}
+		if newOy < 0 {
+			newOy = 0
+		}
+		return v.View.SetOrigin(0, newOy)
+	}
+}
+
+func (v *KeysView) close(g *gocui.Gui, _ *gocui.View) error {
+	g.DeleteKeybindings(k_KeysView)
+	if err := g.DeleteView(k_KeysView); err != nil {
+		return err
+	}
+	if len(v.previousView) > 0 {
+		if _, err := g.SetCurrentView(v.previousView); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -21,10 +22,30 @@ var (
 
 var g_TargetRef string
 
+// g_ExpandMode is what is expanded in a new diff, one of the keys of k_ExpandModes.
+var g_ExpandMode string
+
+// g_FinishRemaining commits the remaining changes as the last part after the first one is confirmed,
+// instead of asking whether to continue splitting.
+var g_FinishRemaining bool
+
+// k_ExpandModes are the expansion of files and chunks for each expand mode.
+var k_ExpandModes = map[string][2]difftree.ExpansionState{
+	"none":  {difftree.Collapsed, difftree.Collapsed},
+	"files": {difftree.Expanded, difftree.Collapsed},
+	"all":   {difftree.Expanded, difftree.Expanded},
+}
+
 func init() {
 	flag.BoolVar(&g_Debug_ShowDebugView, "debug-view", false, "")
 	flag.BoolVar(&g_Debug_DontRevertOnError, "debug-no-revert-on-error", false, "")
 	flag.BoolVar(&g_Debug_ShowDebugView, "debug-dump-patch-on-apply", false, "")
+	flag.StringVar(&g_ExpandMode, "expand", "none", "what to expand when a diff is shown: none, files or all; "+
+		"after the first part, what was expanded stays expanded")
+	flag.BoolVar(&g_FinishRemaining, "finish-remaining", false, "after the first part is confirmed, "+
+		"commit the remaining changes as the last part without asking whether to continue splitting")
+	flag.BoolVar(&g_NoTUI, "no-tui", false, "print the parts and their range-diff before the branch is rewritten, "+
+		"and confirm on the terminal, instead of in the UI")
 	flag.Parse()
 	if flag.NArg() == 0 {
 		g_TargetRef = "HEAD"
@@ -45,6 +66,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if _, ok := k_ExpandModes[g_ExpandMode]; !ok {
+		color.Red("Unknown expand mode %q; it can be none, files or all.", g_ExpandMode)
+		os.Exit(1)
+	}
+
+	if err := loadKeymap(); err != nil {
+		color.Red(err.Error())
+		os.Exit(1)
+	}
+
 	// get a hash so the reference is valid when we move around.
 	var err error
 	if g_TargetRef, err = git.RevParse(g_TargetRef); err != nil {
@@ -88,24 +119,66 @@ func main() {
 		log.Panicln(err)
 	}
 
+	abort := func() {
+		git.Checkout(originalBranchName)
+		// this is the ONLY place we delete a branch, the unneeded backup branch because we aborted.
+		git.ForceDeleteBranch(backupBranchName)
+		os.Exit(0)
+	}
+
+	// the previous diff and where its cursor was, to carry them over to the next part.
+	var previous *difftree.Commit
+	var cursor difftree.Position
+	// committedPart is the part that was just committed, to ask whether to continue splitting after
+	// it, or 0.
+	committedPart := 0
+	// notice is shown when the UI opens, about what happened since it was closed.
+	notice := ""
 	for {
-		// get a patch format of the diff described by the selected commit
-		patch, err := git.Diff("HEAD", g_TargetRef)
-		if err != nil {
-			log.Println(err)
-			log.Panicln(patch.String())
-		}
+		// part counts the commits the original is split into.
+		part := len(g_Parts) + 1
 
-		commit, err := difftree.ParseCommit(patch)
+		// get a patch format of the diff described by the selected commit. The remaining changes are
+		// bundled up without the options, as they could leave some out.
+		options := g_DiffOptions
+		if finishUp {
+			options = k_DefaultDiffOptions
+		}
+		commit, err := parseDiff(options)
 		if err != nil {
 			log.Panicln(err)
-		} else if len(commit.Files) == 0 {
-			// no more changes, rebase and quit
+		} else if len(commit.Files) == 0 && options != k_DefaultDiffOptions {
+			// only changes left out by the options, like whitespace, remain, so the options are reset.
+			options, g_DiffOptions = k_DefaultDiffOptions, k_DefaultDiffOptions
+			if commit, err = parseDiff(options); err != nil {
+				log.Panicln(err)
+			}
+		}
+		expansion := k_ExpandModes[g_ExpandMode]
+		commit.SetExpansion(expansion[0], expansion[1])
+		if previous != nil {
+			commit.CarryExpansion(previous)
+		}
+
+		if len(commit.Files) == 0 {
+			// no more changes; once the parts are reviewed, rebase and quit
+			if len(g_Parts) > 0 {
+				if err := runSummary(startRef, backupBranchName); err == gocui.ErrQuit {
+					abort()
+				} else if err != ErrConfirm {
+					log.Panicln(err)
+				}
+			}
 			if err := git.Rebase("HEAD", originalBranchName); err != nil {
 				log.Panicln(err)
 			}
 			os.Exit(0)
 		}
+		if g_Revised != nil {
+			if err := selectPart(commit, options, g_Revised.Hash); err != nil {
+				log.Panicln(err)
+			}
+		}
 		commit.Description, err = git.FormatShowRefDescription(
 			g_TargetRef,
 			`# Original commit: %H
@@ -113,15 +186,24 @@ func main() {
 # Date:   %ad
 #
 # The original commit message is below. You may edit it as you see fit.
-%B
-
 `,
 		)
 		if err != nil {
 			log.Panicln(err)
 		}
+		// a revised part keeps its message.
+		messageRef := g_TargetRef
+		if g_Revised != nil {
+			messageRef = g_Revised.Hash
+		}
+		if message, err := git.FormatShowRefDescription(messageRef, "%B"); err != nil {
+			log.Panicln(err)
+		} else {
+			commit.Description += "\n" + message + "\n\n"
+		}
 
-		doOnConfirm := func() error {
+		// doOnConfirm commits the selection of the commit, whose diff was generated with the options.
+		doOnConfirm := func(options DiffOptions) error {
 			patch := commit.AsPatchString()
 			if g_Debug_DumpPatchToFile {
 				f, err := os.CreateTemp("", "git-split*.patch")
@@ -131,7 +213,7 @@ func main() {
 				f.WriteString(patch)
 			}
 
-			if err = git.ApplyPatch(strings.NewReader(patch)); err != nil {
+			if err = applyPatch(options, strings.NewReader(patch)); err != nil {
 				if g_Debug_DontRevertOnError {
 					git.Checkout(originalBranchName)
 				}
@@ -160,14 +242,33 @@ func main() {
 			return nil
 		}
 
+		// commitPart commits the selection as the next part, and reapplies the parts waiting after it if
+		// it was revised, returning a message if any of them didn't apply.
+		commitPart := func(options DiffOptions) (string, error) {
+			if err := doOnConfirm(options); err != nil {
+				return "", err
+			}
+			summary, err := summarizePart("HEAD")
+			if err != nil {
+				return "", err
+			}
+			g_Parts = append(g_Parts, summary)
+			replay := g_Replay
+			g_Revised, g_Replay = nil, nil
+			return replayParts(replay)
+		}
+
 		if !finishUp {
-			g, err := gocui.NewGui(gocui.OutputNormal, false)
+			// the backgrounds of added and deleted lines are 256-colour.
+			g, err := gocui.NewGui(gocui.Output256, false)
 			if err != nil {
 				log.Panicln(err)
 			}
 
-			g.SetManagerFunc(layoutFn(commit))
+			g.SetManagerFunc(layoutFn(commit, part, cursor, committedPart, notice))
+			committedPart, notice = 0, ""
 			g.Cursor = true
+			g.Mouse = true
 			g.FgColor = gocui.ColorWhite
 			g.BgColor = gocui.ColorBlack
 			g.SelBgColor = gocui.ColorWhite
@@ -177,55 +278,107 @@ func main() {
 				log.Panicln(err)
 			}
 
-			if err := g.MainLoop(); err != nil && err != gocui.ErrQuit && err != ErrConfirm {
+			var revise *ReviseRequest
+			if err := g.MainLoop(); err != nil && err != gocui.ErrQuit && err != ErrConfirm && err != ErrFinish && !errors.As(err, &revise) {
 				log.Panicln(err)
 			} else if err == gocui.ErrQuit {
 				g.Close()
-				git.Checkout(originalBranchName)
-				// this is the ONLY place we delete a branch, the unneeded backup branch because we
-				// aborted.
-				git.ForceDeleteBranch(backupBranchName)
-				os.Exit(0)
+				abort()
+			} else if err == ErrFinish {
+				// the remaining changes are diffed again without the options and committed as they are.
+				g.Close()
+				finishUp = true
 			} else if err == ErrConfirm {
+				if v, err := g.View(k_MainView); err == nil {
+					_, y := v.Cursor()
+					cursor = difftree.PositionOf(commit.LineMap[y])
+				}
+				previous = commit
 				g.Close()
-				if err := doOnConfirm(); err != nil {
+				// the diff may have been generated again with other options in the UI.
+				if notice, err = commitPart(g_DiffOptions); err != nil {
 					log.Panicln(err)
 				}
-
-				if isDifferent, err := git.IsDifferent("HEAD", g_TargetRef); err != nil {
+				// if any changes remain, the next part asks whether to continue splitting, unless
+				// finishing was asked for up front.
+				committedPart = part
+				finishUp = g_FinishRemaining
+			} else if revise != nil {
+				if v, err := g.View(k_MainView); err == nil {
+					_, y := v.Cursor()
+					cursor = difftree.PositionOf(commit.LineMap[y])
+				}
+				previous = commit
+				g.Close()
+				// the selection of the part being split is dropped, and a revision in progress is
+				// abandoned, leaving its parts as they were.
+				abandonRevision()
+				removed, err := checkoutBeforePart(revise.Part, startRef)
+				if err != nil {
 					log.Panicln(err)
-				} else if isDifferent {
-					fmt.Print("Do you want to continue splitting? [Y/n]: ")
-					nextChar := []byte{0}
-					for nextChar[0] != 'y' && nextChar[0] != 'n' && nextChar[0] != '\n' {
-						if _, err := os.Stdin.Read(nextChar); err != nil {
-							log.Panicln(err)
-						}
-					}
-					if nextChar[0] == 'n' {
-						finishUp = true
+				}
+				if revise.Merge {
+					if notice, err = replayParts(removed[1:]); err != nil {
+						log.Panicln(err)
+					} else if len(notice) == 0 {
+						notice = fmt.Sprintf("Part %d was merged back into the remaining changes", revise.Part)
 					}
+				} else {
+					g_Revised, g_Replay = &removed[0], removed[1:]
+					notice = fmt.Sprintf("Revising part %d; the parts after it are reapplied when it is confirmed", revise.Part)
 				}
 			}
 		} else {
-			if err := doOnConfirm(); err != nil {
+			// any parts that don't apply after a revised one are left in the remaining changes, and
+			// bundled up with them.
+			if _, err := commitPart(options); err != nil {
 				log.Panicln(err)
 			}
 		}
 	}
 }
 
-func layoutFn(c *difftree.Commit) func(g *gocui.Gui) error {
+func layoutFn(c *difftree.Commit, part int, cursor difftree.Position, committedPart int, notice string) func(g *gocui.Gui) error {
 	return func(g *gocui.Gui) error {
+		opened := false
 		if _, err := LayoutHelpView(g); err != nil {
 			return err
 		}
 
 		if mainView, isInit, err := LayoutMainView(g); err != nil {
 			return err
-		} else if isInit {
+		} else if opened = isInit; isInit {
 			mainView.SetCommit(c)
+			if node := c.Find(cursor); node != nil {
+				mainView.View.SetCursor(0, mainView.lineNumberOf(node))
+				fixScroll(mainView.View)
+			}
 			g.SetCurrentView(mainView.Name())
+			setMouseModes(g)
+		}
+
+		if _, err := LayoutFilterView(g, c); err != nil {
+			return err
+		}
+
+		if _, err := LayoutPartsView(g, c); err != nil {
+			return err
+		}
+
+		if _, err := LayoutStatusView(g, c, part); err != nil {
+			return err
+		}
+
+		if err := LayoutPromptView(g); err != nil {
+			return err
+		}
+
+		if err := LayoutKeysView(g); err != nil {
+			return err
+		}
+
+		if err := LayoutContinueView(g); err != nil {
+			return err
 		}
 
 		if g_Debug_ShowDebugView {
@@ -234,18 +387,24 @@ func layoutFn(c *difftree.Commit) func(g *gocui.Gui) error {
 			}
 		}
 
+		// the notice and continue view are opened over the other views.
+		if opened && len(notice) > 0 {
+			if err := ShowMessage(g, notice); err != nil {
+				return err
+			}
+		}
+		if opened && committedPart > 0 {
+			if err := OpenContinueView(g, committedPart); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
 }
 
 func setGlobalKeybindings(g *gocui.Gui) error {
-	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
-		return err
-	}
-	if err := g.SetKeybinding("", 'q', gocui.ModNone, quit); err != nil {
-		return err
-	}
-	return nil
+	return bindKeys(g, nil)
 }
 
 func quit(g *gocui.Gui, v *gocui.View) error {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/awesome-gocui/gocui"
@@ -11,6 +12,9 @@ import (
 
 const k_MainView = "main"
 
+// k_EditRejectedHeader starts the line added to the top of a rejected edit when it is reopened.
+const k_EditRejectedHeader = "# Your edit was rejected: "
+
 var ErrConfirm = fmt.Errorf("confirm changes and quit")
 
 type MainView struct {
@@ -18,12 +22,26 @@ type MainView struct {
 	*gocui.View
 
 	commit *ir.Commit
+	// sideBySide shows the lines of chunks in columns for the old and new file.
+	sideBySide bool
+	// wrap soft wraps the lines of chunks in the unified layout.
+	wrap bool
+
+	// search is the last search, which searchNext repeats.
+	search         *regexp.Regexp
+	searchBackward bool
+
+	// visualAnchor is the node where the range of visual mode starts, or nil outside visual mode.
+	visualAnchor ir.Selectable
+
+	// the selection from before each change, and from before each undo, for undo and redo.
+	undoStack, redoStack []ir.SelectionSnapshot
 }
 
 func LayoutMainView(g *gocui.Gui) (v *MainView, isInit bool, err error) {
 	v = &MainView{Gui: g}
-	maxX, maxY := g.Size()
-	v.View, err = g.SetView(k_MainView, 0, k_HelpViewHeight-1, maxX-1, maxY-1, 0)
+	x0, y0, x1, y1 := mainViewBounds(g)
+	v.View, err = g.SetView(k_MainView, x0, y0, x1, y1, 0)
 	if err != nil {
 		if err == gocui.ErrUnknownView {
 			isInit = true
@@ -44,6 +62,16 @@ func LayoutMainView(g *gocui.Gui) (v *MainView, isInit bool, err error) {
 	return v, isInit, nil
 }
 
+// mainViewBounds leaves room for the parts view on the right if it is shown.
+func mainViewBounds(g *gocui.Gui) (x0, y0, x1, y1 int) {
+	maxX, maxY := g.Size()
+	x1 = maxX - 1
+	if showParts(g) {
+		x1 -= k_PartsViewWidth
+	}
+	return 0, k_HelpViewHeight - 1, x1, maxY - 1
+}
+
 func (v *MainView) SetCommit(c *ir.Commit) {
 	v.commit = c
 	v.printContent()
@@ -51,46 +79,16 @@ func (v *MainView) SetCommit(c *ir.Commit) {
 }
 
 func (v *MainView) setKeybindings() error {
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowUp, gocui.ModNone, moveCursor(v, -1)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowUp, gocui.ModShift, moveCursor(v, -15)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyPgup, gocui.ModNone, moveCursor(v, -15)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowDown, gocui.ModNone, moveCursor(v, 1)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowDown, gocui.ModShift, moveCursor(v, 15)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyPgdn, gocui.ModNone, moveCursor(v, 15)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowLeft, gocui.ModNone, setExpansionState(v, ir.Collapsed)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowLeft, gocui.ModShift, setExpansionAll(v, ir.Collapsed)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowRight, gocui.ModNone, setExpansionState(v, ir.Expanded)); err != nil {
+	if err := bindKeys(v.Gui, v); err != nil {
 		return err
 	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeyArrowRight, gocui.ModShift, setExpansionAll(v, ir.Expanded)); err != nil {
+	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.MouseLeft, gocui.ModNone, click(v)); err != nil {
 		return err
 	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.KeySpace, gocui.ModNone, toggleSelection(v)); err != nil {
+	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.MouseWheelUp, gocui.ModNone, scroll(v, -k_WheelLines)); err != nil {
 		return err
 	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), 'c', gocui.ModNone, confirm(v)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), 'a', gocui.ModNone, selectAll(v, ir.Selected)); err != nil {
-		return err
-	}
-	if err := v.Gui.SetKeybinding(v.View.Name(), 'A', gocui.ModNone, selectAll(v, ir.Deselected)); err != nil {
+	if err := v.Gui.SetKeybinding(v.View.Name(), gocui.MouseWheelDown, gocui.ModNone, scroll(v, k_WheelLines)); err != nil {
 		return err
 	}
 	return nil
@@ -98,14 +96,56 @@ func (v *MainView) setKeybindings() error {
 
 func (v *MainView) printContent() {
 	x, y := v.View.Cursor()
-	_, oY := v.View.Origin()
+	oX, oY := v.View.Origin()
 
 	v.View.Clear()
-	commitString := strings.TrimSpace(v.commit.String())
+	width, _ := v.View.Size()
+	var commitString string
+	if v.sideBySide {
+		commitString = v.commit.SideBySideString(width)
+	} else {
+		v.commit.WrapWidth = 0
+		if v.wrap {
+			v.commit.WrapWidth = width
+		}
+		commitString = v.commit.String()
+	}
+	commitString = strings.TrimSpace(commitString)
 	fmt.Fprint(v.View, commitString)
 
 	v.View.SetCursor(x, y)
-	v.View.SetOrigin(0, oY)
+	v.View.SetOrigin(v.clampOriginX(oX), oY)
+	v.highlightVisual()
+}
+
+// lineNumberOf returns the first line number that displays the node, or -1 if it isn't displayed. A
+// line is found on the row of its pair in the side-by-side layout, and a pair on its deleted line in
+// the unified layout.
+func (v *MainView) lineNumberOf(node ir.Selectable) int {
+	if pair, ok := node.(*ir.LinePair); ok {
+		node = pair.Delete
+	}
+	for i, n := range v.commit.LineMap {
+		if pair, ok := n.(*ir.LinePair); ok && (pair.Delete == node || pair.Add == node) {
+			return i
+		} else if n == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// chunkOf returns the chunk of a chunk, line or line pair, or nil for a file.
+func chunkOf(node ir.Selectable) *ir.Chunk {
+	switch node := node.(type) {
+	case *ir.Chunk:
+		return node
+	case *ir.Line:
+		return node.Parent
+	case *ir.LinePair:
+		return node.Delete.Parent
+	}
+	return nil
 }
 
 func fixScroll(v *gocui.View) {
@@ -132,6 +172,10 @@ func moveCursor(v *MainView, dy int) func(*gocui.Gui, *gocui.View) error {
 			if cy >= len(v.commit.LineMap) || cy < 0 {
 				return nil
 			}
+			if v.isContinuation(cy) {
+				cy += d1y
+				continue
+			}
 			switch val := v.commit.LineMap[cy].(type) {
 			case *ir.Line:
 				if val.Op == gitdiff.OpContext {
@@ -145,6 +189,10 @@ func moveCursor(v *MainView, dy int) func(*gocui.Gui, *gocui.View) error {
 		}
 		v.View.SetCursor(0, cy)
 		fixScroll(v.View)
+		if v.visualAnchor != nil {
+			// the colours of the range need redrawing as it changes.
+			v.printContent()
+		}
 		return nil
 	}
 }
@@ -177,9 +225,9 @@ func setExpansionState(v *MainView, state ir.ExpansionState) func(*gocui.Gui, *g
 			} else {
 				node.Expanded = state
 			}
-		case *ir.Line:
+		case *ir.Line, *ir.LinePair:
 			if state == ir.Collapsed {
-				y = node.Parent.LineNumber
+				y = chunkOf(node).LineNumber
 			}
 		}
 		v.printContent()
@@ -193,7 +241,7 @@ func setExpansionAll(v *MainView, state ir.ExpansionState) func(*gocui.Gui, *goc
 	return func(_ *gocui.Gui, _ *gocui.View) error {
 		x, y := v.View.Cursor()
 		i := v.commit.LineMap[y]
-		v.commit.ForEachNode(
+		v.commit.ForEachShownNode(
 			func(f *ir.File) error {
 				f.Expanded = state
 				return nil
@@ -208,11 +256,8 @@ func setExpansionAll(v *MainView, state ir.ExpansionState) func(*gocui.Gui, *goc
 
 		if state == ir.Collapsed {
 			// in this case we want to jump to the file that contained the previously-selected line
-			switch node := i.(type) {
-			case *ir.Chunk:
-				y = node.Parent.LineNumber
-			case *ir.Line:
-				y = node.Parent.Parent.LineNumber
+			if chunk := chunkOf(i); chunk != nil {
+				y = chunk.Parent.LineNumber
 			}
 			v.View.SetCursor(x, y)
 		}
@@ -221,12 +266,105 @@ func setExpansionAll(v *MainView, state ir.ExpansionState) func(*gocui.Gui, *goc
 	}
 }
 
-func toggleSelection(v *MainView) func(*gocui.Gui, *gocui.View) error {
+// toggleSelection toggles the node under the cursor, or the range in visual mode. A paired line is
+// toggled along with the other line of its pair, unless single is true. The side-by-side layout shows
+// a pair on one row, so both of its lines are toggled even if single is true, and the user is told.
+func toggleSelection(v *MainView, single bool) func(*gocui.Gui, *gocui.View) error {
 	return func(g *gocui.Gui, _ *gocui.View) error {
+		if v.visualAnchor != nil {
+			v.setVisualSelection(ir.Selected, true)
+			return nil
+		}
+
 		_, y := v.View.Cursor()
 		i := v.commit.LineMap[y]
-		i.ToggleSelection()
+		if l, ok := i.(*ir.Line); ok && l.Pair != nil && !single {
+			i = l.Pair
+		}
+		v.changeSelection(i.ToggleSelection)
+		v.printContent()
+		if _, ok := i.(*ir.LinePair); ok && single {
+			return ShowMessage(g, "Both lines of a pair are toggled in the side-by-side layout; use the unified layout to toggle one")
+		}
+		return nil
+	}
+}
+
+func splitChunk(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		x, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+		chunk := chunkOf(node)
+		if chunk == nil {
+			return nil
+		}
+
+		chunks := chunk.Split()
+		v.printContent()
+
+		// a line stays selected, but a split chunk no longer exists, so we move to the first new one.
+		if _, ok := node.(*ir.Chunk); ok {
+			y = chunks[0].LineNumber
+		} else {
+			y = v.lineNumberOf(node)
+		}
+		v.View.SetCursor(x, y)
+		fixScroll(v.View)
+		return nil
+	}
+}
+
+func editChunk(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		x, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+		chunk := chunkOf(node)
+		if chunk == nil {
+			return nil
+		}
+
+		// like `git add -p`, a rejected edit is reopened with the problem at the top.
+		s := chunk.AsEditString()
+		for {
+			edited, err := editInEditor(v.Gui, s)
+			if err != nil {
+				// an editor exiting with an error, like vim's :cq, aborts the edit.
+				return ShowMessage(g, fmt.Sprintf("Edit aborted: %s", err))
+			}
+			if err = chunk.ApplyEdit(edited); err == ir.ErrEditAborted {
+				break
+			} else if err != nil {
+				// only the latest problem is shown.
+				for strings.HasPrefix(edited, k_EditRejectedHeader) {
+					_, edited, _ = strings.Cut(edited, "\n")
+				}
+				s = fmt.Sprintf("%s%s\n%s", k_EditRejectedHeader, err, edited)
+				continue
+			}
+			break
+		}
+
 		v.printContent()
+		if y >= len(v.commit.LineMap) || v.commit.LineMap[y] != node {
+			// the lines of the chunk have been replaced, so we go to the chunk itself.
+			y = chunk.LineNumber
+		}
+		v.View.SetCursor(x, y)
+		fixScroll(v.View)
+		return nil
+	}
+}
+
+// toggleSideBySide switches between the unified and side-by-side layouts, keeping the cursor on the
+// same node.
+func toggleSideBySide(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		x, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+		v.sideBySide = !v.sideBySide
+		v.printContent()
+		v.View.SetCursor(x, v.lineNumberOf(node))
+		fixScroll(v.View)
 		return nil
 	}
 }
@@ -237,22 +375,30 @@ func confirm(v *MainView) func(*gocui.Gui, *gocui.View) error {
 	}
 }
 
+// selectAll sets the selection of every shown node, or of the range in visual mode.
 func selectAll(v *MainView, state ir.SelectionState) func(*gocui.Gui, *gocui.View) error {
 	return func(_ *gocui.Gui, _ *gocui.View) error {
-		v.commit.ForEachNode(
-			func(f *ir.File) error {
-				f.SetSelection(state)
-				return nil
-			},
-			func(_ *ir.File, c *ir.Chunk) error {
-				c.SetSelection(state)
-				return nil
-			},
-			func(_ *ir.File, _ *ir.Chunk, l *ir.Line) error {
-				l.SetSelection(state)
-				return nil
-			},
-		)
+		if v.visualAnchor != nil {
+			v.setVisualSelection(state, false)
+			return nil
+		}
+
+		v.changeSelection(func() {
+			v.commit.ForEachShownNode(
+				func(f *ir.File) error {
+					f.SetSelection(state)
+					return nil
+				},
+				func(_ *ir.File, c *ir.Chunk) error {
+					c.SetSelection(state)
+					return nil
+				},
+				func(_ *ir.File, _ *ir.Chunk, l *ir.Line) error {
+					l.SetSelection(state)
+					return nil
+				},
+			)
+		})
 		v.printContent()
 		return nil
 	}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+
+	"github.com/awesome-gocui/gocui"
+	ir "github.com/smithjacobj/git-split/difftree"
+)
+
+// k_MouseModes has the terminal report mouse clicks, drags and the wheel. gocui also enables
+// reporting of mouse movement, which would move the cursor to wherever the mouse is, so we disable
+// it again.
+const k_MouseModes = "\x1b[?1003l\x1b[?1000h\x1b[?1002h\x1b[?1006h"
+
+// k_WheelLines is how many lines the mouse wheel scrolls.
+const k_WheelLines = 3
+
+// setMouseModes sets the mouse reporting we want, if the mouse is enabled. gocui enables the mouse
+// when its main loop starts, but not when resuming from gocui.Suspend, so this is needed for both.
+func setMouseModes(g *gocui.Gui) {
+	if g.Mouse {
+		os.Stdout.WriteString(k_MouseModes)
+	}
+}
+
+// click handles a click, which has already moved the cursor to the clicked row. A click on a
+// checkbox toggles the selection like spacebar, and one on an expander expands or collapses it. A
+// click on a continued row moves to the row it continues.
+func click(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		x, y := v.View.Cursor()
+		if y >= len(v.commit.LineMap) {
+			y = len(v.commit.LineMap) - 1
+		}
+		column := ir.ColumnAt(v.commit.LineMap[y], x)
+		for ; v.isContinuation(y); y-- {
+			column = ir.OtherColumn
+		}
+		v.View.SetCursor(0, y)
+
+		node := v.commit.LineMap[y]
+		switch column {
+		case ir.CheckboxColumn:
+			if l, ok := node.(*ir.Line); ok && l.Pair != nil {
+				node = l.Pair
+			}
+			v.changeSelection(node.ToggleSelection)
+		case ir.ExpanderColumn:
+			state := ir.Expanded
+			if f, ok := node.(*ir.File); ok && f.Expanded == ir.Expanded {
+				state = ir.Collapsed
+			} else if c, ok := node.(*ir.Chunk); ok && c.Expanded == ir.Expanded {
+				state = ir.Collapsed
+			}
+			return setExpansionState(v, state)(g, nil)
+		}
+		v.printContent()
+		return nil
+	}
+}
+
+// scroll scrolls the view by dy lines, keeping the cursor at the row under the mouse and the
+// horizontal scroll as it is.
+func scroll(v *MainView, dy int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		_, height := v.View.Size()
+		ox, oy := v.View.Origin()
+		_, cy := v.View.Cursor()
+
+		newOy := oy + dy
+		if maxOy := len(v.commit.LineMap) - height; newOy > maxOy {
+			newOy = maxOy
+		}
+		if newOy < 0 {
+			newOy = 0
+		}
+		v.View.SetOrigin(ox, newOy)
+		v.View.SetCursor(0, cy+newOy-oy)
+		if v.visualAnchor != nil {
+			v.printContent()
+		}
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/fatih/color"
+	ir "github.com/smithjacobj/git-split/difftree"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+const k_PartsView = "parts"
+
+// k_PartsViewWidth is the width of the parts view, which is only shown if the screen is at least
+// k_PartsViewMinScreenWidth wide, to leave room for the diff.
+const k_PartsViewWidth = 36
+const k_PartsViewMinScreenWidth = 100
+
+// g_ShowParts is whether the parts view is shown, which is kept for the following parts.
+var g_ShowParts = true
+
+// g_Parts are the commits made so far, in order.
+var g_Parts []PartSummary
+
+// PartSummary describes a commit made for a part of the split.
+type PartSummary struct {
+	// Hash is the full hash of the commit, and ShortHash the abbreviated one.
+	Hash, ShortHash string
+	Subject         string
+	Files           int
+	Adds, Deletes   int
+}
+
+// PartsView is a sidebar listing the parts committed so far and what remains to be split, so that
+// the progress of the split can be seen. While a part is revised, the parts waiting to be reapplied
+// after it are listed last.
+type PartsView struct {
+	*gocui.Gui
+	*gocui.View
+}
+
+// summarizePart describes the commit at the ref.
+func summarizePart(ref string) (PartSummary, error) {
+	cmd := git.GitCmd("show", "--numstat", "--format=%H%x09%h%x09%s", ref)
+	output, err := cmd.FormatOutput(cmd.Output())
+	if err != nil {
+		return PartSummary{}, err
+	}
+
+	lines := strings.Split(output, "\n")
+	var p PartSummary
+	if fields := strings.SplitN(lines[0], "\t", 3); len(fields) == 3 {
+		p.Hash, p.ShortHash, p.Subject = fields[0], fields[1], fields[2]
+	}
+	for _, line := range lines[1:] {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		p.Files++
+		// binary files are counted with "-" instead of lines.
+		adds, _ := strconv.Atoi(fields[0])
+		deletes, _ := strconv.Atoi(fields[1])
+		p.Adds += adds
+		p.Deletes += deletes
+	}
+	return p, nil
+}
+
+// showParts returns whether the parts view fits on the screen and hasn't been hidden.
+func showParts(g *gocui.Gui) bool {
+	maxX, _ := g.Size()
+	return g_ShowParts && maxX >= k_PartsViewMinScreenWidth
+}
+
+// LayoutPartsView shows the parts committed so far and the changes of the commit that remain, or
+// removes the view if it isn't shown.
+func LayoutPartsView(g *gocui.Gui, c *ir.Commit) (v *PartsView, err error) {
+	if !showParts(g) {
+		if err := g.DeleteView(k_PartsView); err != nil && err != gocui.ErrUnknownView {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	v = &PartsView{Gui: g}
+	maxX, maxY := g.Size()
+	if v.View, err = g.SetView(k_PartsView, maxX-k_PartsViewWidth, k_HelpViewHeight-1, maxX-1, maxY-2, 0); err != nil && err != gocui.ErrUnknownView {
+		return nil, err
+	}
+	v.View.Title = "Parts"
+	v.printContent(c.Stats())
+	return v, nil
+}
+
+func (v *PartsView) printContent(s ir.Stats) {
+	v.View.Clear()
+	lineCount := 0
+	writeLine := func(format string, a ...interface{}) {
+		fmt.Fprintf(v.View, format+"\n", a...)
+		lineCount++
+	}
+
+	if len(g_Parts) == 0 {
+		writeLine(color.New(color.Faint).Sprint("nothing committed yet"))
+	}
+	for i, p := range g_Parts {
+		writeLine("%d %s %s", i+1, color.YellowString(p.ShortHash), p.Subject)
+		writeLine("  %d files %s %s", p.Files, color.GreenString("+%d", p.Adds), color.RedString("-%d", p.Deletes))
+	}
+	writeLine("")
+	if g_Revised != nil {
+		writeLine("remaining, revising part %d %s", len(g_Parts)+1, color.YellowString(g_Revised.ShortHash))
+	} else {
+		writeLine("remaining, being split as part %d", len(g_Parts)+1)
+	}
+	writeLine("  %d files %s %s", s.Files, color.GreenString("+%d", s.Adds), color.RedString("-%d", s.Deletes))
+	if len(g_Replay) > 0 {
+		writeLine("")
+		writeLine("reapplied after part %d:", len(g_Parts)+1)
+		for i, p := range g_Replay {
+			writeLine("%d %s %s", len(g_Parts)+2+i, color.YellowString(p.ShortHash), p.Subject)
+		}
+	}
+
+	// the latest parts and the remainder are kept in view.
+	_, height := v.View.Size()
+	if lineCount > height {
+		v.View.SetOrigin(0, lineCount-height)
+	} else {
+		v.View.SetOrigin(0, 0)
+	}
+}
+
+// toggleParts shows or hides the parts view, reprinting the main view at its new width.
+func toggleParts(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		g_ShowParts = !g_ShowParts
+		if maxX, _ := g.Size(); g_ShowParts && maxX < k_PartsViewMinScreenWidth {
+			return ShowMessage(g, "The screen is too narrow to show the parts")
+		}
+
+		x0, y0, x1, y1 := mainViewBounds(g)
+		if _, err := g.SetView(k_MainView, x0, y0, x1, y1, 0); err != nil {
+			return err
+		}
+		x, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+		v.printContent()
+		v.View.SetCursor(x, v.lineNumberOf(node))
+		fixScroll(v.View)
+		return nil
+	}
+}
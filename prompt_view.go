@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+const k_PromptView = "prompt"
+const k_MessageView = "message"
+const k_MessageDuration = 3 * time.Second
+
+// ErrCancelPrompt is returned by the onChange function of a prompt to close it as if it was cancelled.
+var ErrCancelPrompt = fmt.Errorf("cancel the prompt")
+
+// g_MessageCount identifies the latest message, so that an earlier message's timer doesn't remove it.
+var g_MessageCount = 0
+
+// PromptView is a line at the bottom of the screen for typing a search or command after a prompt,
+// like "/". It is closed with enter, or with escape or backspacing over the prompt to cancel.
+type PromptView struct {
+	*gocui.Gui
+	*gocui.View
+
+	prompt       string
+	previousView string
+	// onChange is called with the input whenever it is edited, and can return ErrCancelPrompt to
+	// cancel the prompt.
+	onChange func(input string) error
+	// onDone is called with the input when the prompt is closed, with ok false if it was cancelled.
+	onDone func(input string, ok bool) error
+}
+
+// k_PromptKeys are the keys of the prompt, which are listed with all keys. Those without a handler
+// are handled by the editor.
+var k_PromptKeys = []struct {
+	keys    []string
+	usage   string
+	handler func(v *PromptView) func(*gocui.Gui, *gocui.View) error
+}{
+	{[]string{"enter"}, "accept", func(v *PromptView) func(*gocui.Gui, *gocui.View) error { return v.close(true) }},
+	{[]string{"esc"}, "cancel", func(v *PromptView) func(*gocui.Gui, *gocui.View) error { return v.close(false) }},
+	{[]string{"backspace"}, "cancel, when nothing has been typed", nil},
+	{[]string{"left", "right"}, "move in the text", nil},
+}
+
+// OpenPromptView opens the prompt with the input to edit and makes it the current view until it is
+// closed.
+func OpenPromptView(g *gocui.Gui, prompt, input string, onChange func(string) error, onDone func(string, bool) error) error {
+	v := &PromptView{Gui: g, prompt: prompt, onChange: onChange, onDone: onDone}
+	g.DeleteView(k_MessageView)
+	if current := g.CurrentView(); current != nil {
+		v.previousView = current.Name()
+	}
+
+	var err error
+	maxX, maxY := g.Size()
+	if v.View, err = g.SetView(k_PromptView, -1, maxY-2, maxX, maxY, 0); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	v.View.Frame = false
+	v.View.Editable = true
+	v.View.Editor = gocui.EditorFunc(v.edit)
+	v.View.Clear()
+	v.View.WriteString(prompt + input)
+	v.View.SetCursor(len(prompt)+len([]rune(input)), 0)
+
+	for _, b := range k_PromptKeys {
+		if b.handler == nil {
+			continue
+		}
+		for _, name := range b.keys {
+			key, mod, err := parseKey(name)
+			if err != nil {
+				return err
+			}
+			if err := g.SetKeybinding(k_PromptView, key, mod, b.handler(v)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = g.SetCurrentView(k_PromptView)
+	return err
+}
+
+// ShowMessage shows a message where the prompt is, like the result of a command, for a few seconds.
+func ShowMessage(g *gocui.Gui, message string) error {
+	maxX, maxY := g.Size()
+	v, err := g.SetView(k_MessageView, -1, maxY-2, maxX, maxY, 0)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	v.Frame = false
+	v.Clear()
+	v.WriteString(message)
+
+	g_MessageCount++
+	count := g_MessageCount
+	time.AfterFunc(k_MessageDuration, func() {
+		g.Update(func(g *gocui.Gui) error {
+			if count == g_MessageCount {
+				g.DeleteView(k_MessageView)
+			}
+			return nil
+		})
+	})
+	return nil
+}
+
+// LayoutPromptView keeps the prompt and any message at the bottom of the screen, if they are shown.
+func LayoutPromptView(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	for _, name := range []string{k_PromptView, k_MessageView} {
+		if _, err := g.View(name); err != nil {
+			continue
+		}
+		if _, err := g.SetView(name, -1, maxY-2, maxX, maxY, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *PromptView) input() string {
+	return strings.TrimPrefix(strings.TrimRight(v.View.Buffer(), "\n"), v.prompt)
+}
+
+// edit is a single line editor that keeps the prompt in front of the input.
+func (v *PromptView) edit(view *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	x, _ := view.Cursor()
+	switch key {
+	case gocui.KeyBackspace, gocui.KeyBackspace2:
+		if len(v.input()) == 0 {
+			if err := v.close(false)(v.Gui, view); err != nil {
+				v.Gui.Update(func(*gocui.Gui) error { return err })
+			}
+			return
+		} else if x <= len(v.prompt) {
+			return
+		}
+	case gocui.KeyArrowLeft:
+		if x <= len(v.prompt) {
+			return
+		}
+	case gocui.KeyArrowUp, gocui.KeyArrowDown, gocui.KeyTab:
+		return
+	}
+
+	before := v.input()
+	gocui.DefaultEditor.Edit(view, key, ch, mod)
+	if after := v.input(); after != before && v.onChange != nil {
+		err := v.onChange(after)
+		if err == ErrCancelPrompt {
+			err = v.close(false)(v.Gui, view)
+		}
+		if err != nil {
+			v.Gui.Update(func(*gocui.Gui) error { return err })
+		}
+	}
+}
+
+func (v *PromptView) close(ok bool) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		input := v.input()
+		g.DeleteKeybindings(k_PromptView)
+		if err := g.DeleteView(k_PromptView); err != nil {
+			return err
+		}
+		if len(v.previousView) > 0 {
+			if _, err := g.SetCurrentView(v.previousView); err != nil {
+				return err
+			}
+		}
+		return v.onDone(input, ok)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	ir "github.com/smithjacobj/git-split/difftree"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+// g_Revised is the part being split again, whose changes are selected when it is reopened, and
+// g_Replay the parts after it, which are reapplied when it is committed. Neither are in g_Parts
+// until then.
+var g_Revised *PartSummary
+var g_Replay []PartSummary
+
+// ReviseRequest ends the main loop to go back to a part that was committed, either to split it
+// again with its changes selected, or to merge it back into the remaining changes. The parts after
+// it are reapplied on top.
+type ReviseRequest struct {
+	// Part is the number of the part, counting from 1.
+	Part  int
+	Merge bool
+}
+
+func (r *ReviseRequest) Error() string {
+	if r.Merge {
+		return fmt.Sprintf("merge part %d back into the remaining changes", r.Part)
+	}
+	return fmt.Sprintf("revise part %d", r.Part)
+}
+
+// reviseCommand returns a command that goes back to the part given by its number, and merges it
+// back into the remaining changes if merge is set. The selection of the current part is dropped.
+// Any part that was committed can be given, including the one being revised and those waiting to be
+// reapplied after it.
+func reviseCommand(merge bool) commandFunc {
+	return func(_ *MainView, args string) (string, error) {
+		count := len(g_Parts)
+		if g_Revised != nil {
+			count += 1 + len(g_Replay)
+		}
+		if count == 0 {
+			return "", fmt.Errorf("no parts have been committed yet")
+		}
+		part, err := strconv.Atoi(args)
+		if err != nil || part < 1 || part > count {
+			return "", fmt.Errorf("usage: <part>, from 1 to %d", count)
+		}
+		return "", &ReviseRequest{Part: part, Merge: merge}
+	}
+}
+
+// abandonRevision puts the part being revised and those waiting to be reapplied after it back in
+// g_Parts, as they were before it was reopened. Their commits are still on top of g_Parts, so any of
+// them can then be checked out with checkoutBeforePart.
+func abandonRevision() {
+	if g_Revised != nil {
+		g_Parts = append(append(g_Parts, *g_Revised), g_Replay...)
+	}
+	g_Revised, g_Replay = nil, nil
+}
+
+// checkoutBeforePart checks out the commit before the part, and removes the part and the ones after
+// it from g_Parts, returning them.
+func checkoutBeforePart(part int, startRef string) ([]PartSummary, error) {
+	base := startRef
+	if part > 1 {
+		base = g_Parts[part-2].Hash
+	}
+	if err := git.Checkout(base); err != nil {
+		return nil, err
+	}
+
+	removed := append([]PartSummary(nil), g_Parts[part-1:]...)
+	g_Parts = g_Parts[:part-1]
+	return removed, nil
+}
+
+// replayParts cherry-picks the parts onto HEAD in order, adding them to g_Parts, until one doesn't
+// apply. Its changes and those of the parts after it are left in the remaining changes, and a message
+// saying so is returned.
+func replayParts(parts []PartSummary) (string, error) {
+	for i, p := range parts {
+		cmd := git.GitCmd("cherry-pick", p.Hash)
+		if _, err := cmd.FormatOutput(cmd.CombinedOutput()); err != nil {
+			// the part conflicts with the revised parts before it, or they already made its changes.
+			abort := git.GitCmd("cherry-pick", "--abort")
+			if _, err := abort.FormatOutput(abort.CombinedOutput()); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d of the parts after it didn't apply, so they are back in the remaining changes", len(parts)-i), nil
+		}
+
+		summary, err := summarizePart("HEAD")
+		if err != nil {
+			return "", err
+		}
+		g_Parts = append(g_Parts, summary)
+	}
+	return "", nil
+}
+
+// swapParts swaps the part with the one after it, reapplying the parts after them. The parts are left
+// as they were, and false returned, unless both apply in the other order and the result is the same,
+// including when swapping them fails.
+func swapParts(part int, startRef string) (bool, error) {
+	tip, err := git.RevParse("HEAD")
+	if err != nil {
+		return false, err
+	}
+	parts := append([]PartSummary(nil), g_Parts...)
+	restore := func(err error) (bool, error) {
+		g_Parts = parts
+		if checkoutErr := git.Checkout(tip); err == nil {
+			err = checkoutErr
+		}
+		return false, err
+	}
+
+	removed, err := checkoutBeforePart(part, startRef)
+	if err != nil {
+		return restore(err)
+	}
+	removed[0], removed[1] = removed[1], removed[0]
+	notice, err := replayParts(removed)
+	if err != nil || len(notice) > 0 {
+		return restore(err)
+	}
+	if isDifferent, err := git.IsDifferent(tip, "HEAD"); err != nil || isDifferent {
+		return restore(err)
+	}
+	return true, nil
+}
+
+// selectPart selects the changes the commit at the ref made in the diff of the commit, which is
+// from its parent, generated with the options.
+func selectPart(commit *ir.Commit, options DiffOptions, ref string) error {
+	part, err := parseDiffBetween(options, ref+"^", ref)
+	if err != nil {
+		return err
+	}
+	remainder, err := parseDiffBetween(options, ref, g_TargetRef)
+	if err != nil {
+		return err
+	}
+	commit.SelectPart(part, remainder)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	ir "github.com/smithjacobj/git-split/difftree"
+)
+
+// compileSearch compiles a search pattern. Like vim's smartcase, case is ignored unless the pattern
+// has an upper case letter, and a pattern that isn't a valid regular expression is searched for as
+// plain text.
+func compileSearch(pattern string) *regexp.Regexp {
+	flags := ""
+	if strings.ToLower(pattern) == pattern {
+		flags = "(?i)"
+	}
+	re, err := regexp.Compile(flags + pattern)
+	if err != nil {
+		re = regexp.MustCompile(flags + regexp.QuoteMeta(pattern))
+	}
+	return re
+}
+
+// showNode expands the file and chunk containing the node, and moves the cursor to it.
+func (v *MainView) showNode(node ir.Selectable) {
+	if chunk := chunkOf(node); chunk != nil {
+		chunk.Parent.Expanded = ir.Expanded
+		if chunk != node {
+			chunk.Expanded = ir.Expanded
+		}
+	}
+	v.printContent()
+	x, _ := v.View.Cursor()
+	v.View.SetCursor(x, v.lineNumberOf(node))
+	fixScroll(v.View)
+}
+
+// startSearch opens a prompt for a search, which moves to the first match as it is typed. Enter
+// keeps the search for searchNext, and escape goes back to where the search started. An empty
+// search repeats the last one in the new direction. A backward search of k_HelpSearch is cancelled
+// to show all keys.
+func startSearch(v *MainView, backward bool) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		_, y := v.View.Cursor()
+		_, oy := v.View.Origin()
+		start := v.commit.LineMap[y]
+
+		prompt := "/"
+		if backward {
+			prompt = "?"
+		}
+		return OpenPromptView(g, prompt, "",
+			func(input string) error {
+				if backward && input == k_HelpSearch {
+					g.Update(func(g *gocui.Gui) error { return OpenKeysView(g, nil) })
+					return ErrCancelPrompt
+				}
+				node := start
+				if len(input) > 0 {
+					if match := v.commit.Search(compileSearch(input), start, backward); match != nil {
+						node = match
+					}
+				}
+				v.showNode(node)
+				return nil
+			},
+			func(input string, ok bool) error {
+				if !ok {
+					v.showNode(start)
+					v.View.SetOrigin(0, oy)
+					fixScroll(v.View)
+					return nil
+				}
+
+				v.searchBackward = backward
+				if len(input) == 0 {
+					return searchNext(v, false)(g, nil)
+				}
+				v.search = compileSearch(input)
+				return nil
+			},
+		)
+	}
+}
+
+// searchNext moves to the next match of the last search, in the opposite direction if reverse is
+// true.
+func searchNext(v *MainView, reverse bool) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		if v.search == nil {
+			return nil
+		}
+		_, y := v.View.Cursor()
+		if node := v.commit.Search(v.search, v.commit.LineMap[y], v.searchBackward != reverse); node != nil {
+			v.showNode(node)
+		}
+		return nil
+	}
+}
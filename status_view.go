@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/fatih/color"
+	ir "github.com/smithjacobj/git-split/difftree"
+)
+
+const k_StatusView = "status"
+
+// StatusView is a line at the bottom of the screen showing which part of the split this is and how
+// much of the commit is selected for it. The prompt and messages are shown over it.
+type StatusView struct {
+	*gocui.Gui
+	*gocui.View
+}
+
+// LayoutStatusView shows the statistics of the commit's selection for the given part, counting from
+// 1.
+func LayoutStatusView(g *gocui.Gui, c *ir.Commit, part int) (v *StatusView, err error) {
+	v = &StatusView{Gui: g}
+	maxX, maxY := g.Size()
+	if v.View, err = g.SetView(k_StatusView, -1, maxY-2, maxX, maxY, 0); err != nil && err != gocui.ErrUnknownView {
+		return nil, err
+	}
+	v.View.Frame = false
+	v.printContent(c.Stats(), remainderEmpty(c, g_DiffOptions), part)
+	return v, nil
+}
+
+func (v *StatusView) printContent(s ir.Stats, remainderEmpty bool, part int) {
+	v.View.Clear()
+	fmt.Fprintf(v.View, "part %d │ files %d/%d │ chunks %d/%d │ lines %d/%d (%s %s) │ ",
+		part,
+		s.SelectedFiles, s.Files,
+		s.SelectedChunks, s.Chunks,
+		s.SelectedLines, s.Lines,
+		color.GreenString("+%d", s.SelectedAdds), color.RedString("-%d", s.SelectedDeletes),
+	)
+	if remainderEmpty {
+		fmt.Fprint(v.View, color.YellowString("nothing remains; this is the last part"))
+	} else if s.RemainingFiles == 0 {
+		fmt.Fprint(v.View, "remainder: changes left out of the diff or by edits")
+	} else {
+		fmt.Fprintf(v.View, "remainder: %d files, %d lines", s.RemainingFiles, s.Lines-s.SelectedLines)
+	}
+	if g_DiffOptions != k_DefaultDiffOptions {
+		fmt.Fprintf(v.View, " │ git diff %s", g_DiffOptions)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+	"github.com/fatih/color"
+	"github.com/smithjacobj/go-git-utils"
+)
+
+const k_SummaryView = "summary"
+const k_RangeDiffView = "range-diff"
+const k_SummaryHelpView = "summary-help"
+
+// g_NoTUI prints the summary and asks to confirm it on the terminal instead of in the UI.
+var g_NoTUI bool
+
+// SummaryView lists the parts and their range-diff against the original commit before the branch
+// is rewritten with them, so that they can be reviewed, and put in another order where their
+// changes commute. The split is finished with enter or c, and abandoned with the quit keys.
+type SummaryView struct {
+	*gocui.Gui
+	*gocui.View
+
+	rangeDiffView *gocui.View
+	startRef      string
+	backupBranch  string
+}
+
+// rangeDiff compares the original commit with the parts, which both start from startRef.
+func rangeDiff(startRef string) (string, error) {
+	cmd := git.GitCmd("range-diff", "--no-color", startRef+".."+g_TargetRef, startRef+"..HEAD")
+	return cmd.FormatOutput(cmd.CombinedOutput())
+}
+
+// runSummary shows the parts until the split is finished, returning ErrConfirm, or abandoned,
+// returning gocui.ErrQuit. startRef is the commit before the first part, and backupBranch has the
+// original commit.
+func runSummary(startRef, backupBranch string) error {
+	if g_NoTUI {
+		return printSummary(startRef, backupBranch)
+	}
+
+	g, err := gocui.NewGui(gocui.OutputNormal, false)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	v := &SummaryView{Gui: g, startRef: startRef, backupBranch: backupBranch}
+	g.SetManagerFunc(v.layout)
+	g.Cursor = true
+	g.FgColor = gocui.ColorWhite
+	g.BgColor = gocui.ColorBlack
+	g.SelBgColor = gocui.ColorWhite
+	g.SelFgColor = gocui.ColorBlack
+
+	if err := setGlobalKeybindings(g); err != nil {
+		return err
+	}
+	return g.MainLoop()
+}
+
+func (v *SummaryView) layout(g *gocui.Gui) (err error) {
+	// the parts take up to half of the screen, and the range-diff the rest.
+	maxX, maxY := g.Size()
+	listY1 := len(g_Parts) + 1
+	if listY1 > maxY/2 {
+		listY1 = maxY / 2
+	}
+	isInit := false
+	if v.View, err = g.SetView(k_SummaryView, 0, 0, maxX-1, listY1, 0); err == gocui.ErrUnknownView {
+		isInit = true
+	} else if err != nil {
+		return err
+	}
+	if v.rangeDiffView, err = g.SetView(k_RangeDiffView, 0, listY1+1, maxX-1, maxY-2, 0); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+
+	help, err := g.SetView(k_SummaryHelpView, -1, maxY-2, maxX, maxY, 0)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	help.Frame = false
+	help.Clear()
+	fmt.Fprintf(help, "%s: move │ %s: reorder │ %s: scroll range-diff │ %s: rewrite the branch │ %s: abort",
+		color.CyanString("up/down"), color.CyanString("shift-up/down"), color.CyanString("pgup/pgdn"),
+		color.CyanString("enter"), color.CyanString("q"))
+
+	if isInit {
+		v.View.Highlight = true
+		if err := v.printContent(); err != nil {
+			return err
+		}
+		if err := v.setKeybindings(); err != nil {
+			return err
+		}
+		if _, err := g.SetCurrentView(k_SummaryView); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printContent lists the parts and shows their range-diff.
+func (v *SummaryView) printContent() error {
+	v.View.Clear()
+	v.View.Title = fmt.Sprintf("Split into %d parts", len(g_Parts))
+	fmt.Fprint(v.View, partsList())
+
+	output, err := rangeDiff(v.startRef)
+	if err != nil {
+		return err
+	}
+	v.rangeDiffView.Clear()
+	v.rangeDiffView.Title = fmt.Sprintf("Range-diff against the original commit on %s", v.backupBranch)
+	fmt.Fprint(v.rangeDiffView, output)
+	return nil
+}
+
+// partsList lists the parts with their hashes, subjects and numbers of files and lines changed.
+func partsList() string {
+	sb := &strings.Builder{}
+	for i, p := range g_Parts {
+		fmt.Fprintf(sb, "%2d %s %s (%d files %s %s)\n", i+1, color.YellowString(p.ShortHash), p.Subject,
+			p.Files, color.GreenString("+%d", p.Adds), color.RedString("-%d", p.Deletes))
+	}
+	return sb.String()
+}
+
+// printSummary prints the parts and their range-diff, and asks on the terminal whether to rewrite
+// the branch with them. Anything but yes, including the end of the input, abandons the split.
+func printSummary(startRef, backupBranch string) error {
+	output, err := rangeDiff(startRef)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Split into %d parts:\n%s\n", len(g_Parts), partsList())
+	fmt.Printf("Range-diff against the original commit on %s:\n%s\n\n", backupBranch, output)
+	fmt.Print("Rewrite the branch with these parts? [y/N]: ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer == "y" || answer == "yes" {
+		return ErrConfirm
+	}
+	return gocui.ErrQuit
+}
+
+// k_SummaryKeys are the keys of the summary view, which are listed with all keys. The quit keys
+// abandon splitting there too.
+var k_SummaryKeys = []struct {
+	keys    []string
+	usage   string
+	handler func(v *SummaryView) func(*gocui.Gui, *gocui.View) error
+}{
+	{[]string{"up"}, "move up", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.moveCursor(-1) }},
+	{[]string{"down"}, "move down", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.moveCursor(1) }},
+	{[]string{"shift-up"}, "move the part earlier", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.movePart(-1) }},
+	{[]string{"shift-down"}, "move the part later", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.movePart(1) }},
+	{[]string{"pgup"}, "scroll the range-diff up", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.scrollRangeDiff(-1) }},
+	{[]string{"pgdn"}, "scroll the range-diff down", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.scrollRangeDiff(1) }},
+	{[]string{"enter", "c"}, "rewrite the branch with the parts", func(v *SummaryView) func(*gocui.Gui, *gocui.View) error { return v.finish }},
+}
+
+func (v *SummaryView) setKeybindings() error {
+	for _, b := range k_SummaryKeys {
+		for _, name := range b.keys {
+			key, mod, err := parseKey(name)
+			if err != nil {
+				return err
+			}
+			if err := v.Gui.SetKeybinding(k_SummaryView, key, mod, b.handler(v)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *SummaryView) moveCursor(dy int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		_, y := v.View.Cursor()
+		if y += dy; y >= 0 && y < len(g_Parts) {
+			v.View.SetCursor(0, y)
+			fixScroll(v.View)
+		}
+		return nil
+	}
+}
+
+// movePart swaps the part under the cursor with the one before or after it, if their changes
+// commute, and keeps the cursor on it.
+func (v *SummaryView) movePart(dy int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		_, y := v.View.Cursor()
+		other := y + dy
+		if other < 0 || other >= len(g_Parts) {
+			return nil
+		}
+
+		first := y
+		if other < first {
+			first = other
+		}
+		if swapped, err := swapParts(first+1, v.startRef); err != nil {
+			return ShowMessage(g, fmt.Sprintf("Can't swap parts %d and %d: %s", first+1, first+2, strings.ReplaceAll(err.Error(), "\n", " ")))
+		} else if !swapped {
+			return ShowMessage(g, fmt.Sprintf("Parts %d and %d don't apply in the other order, so they can't be swapped", first+1, first+2))
+		}
+		if err := v.printContent(); err != nil {
+			return err
+		}
+		v.View.SetCursor(0, other)
+		fixScroll(v.View)
+		return nil
+	}
+}
+
+// scrollRangeDiff scrolls the range-diff by most of its height, up or down, but not past its end.
+func (v *SummaryView) scrollRangeDiff(direction int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		_, height := v.rangeDiffView.Size()
+		_, oy := v.rangeDiffView.Origin()
+		oy += direction * (height - 1)
+		if last := v.rangeDiffView.LinesHeight() - height; oy > last {
+			oy = last
+		}
+		if oy < 0 {
+			oy = 0
+		}
+		return v.rangeDiffView.SetOrigin(0, oy)
+	}
+}
+
+func (v *SummaryView) finish(_ *gocui.Gui, _ *gocui.View) error {
+	return ErrConfirm
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/awesome-gocui/gocui"
+)
+
+// changeSelection runs change, which changes the selection, and saves the selection from before so
+// that the change can be undone. Changes that leave the selection as it was aren't saved.
+func (v *MainView) changeSelection(change func()) {
+	before := v.commit.SelectionSnapshot()
+	change()
+	if v.commit.SelectionSnapshot().Equal(before) {
+		return
+	}
+	v.undoStack = append(v.undoStack, before)
+	v.redoStack = v.redoStack[:0]
+}
+
+// undo restores the selection from before the last change, or redoes the last undone change if redo
+// is true.
+func undo(v *MainView, redo bool) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		from, to := &v.undoStack, &v.redoStack
+		message := "Undid selection change"
+		if redo {
+			from, to = to, from
+			message = "Redid selection change"
+		}
+
+		if len(*from) == 0 {
+			if redo {
+				return ShowMessage(g, "Nothing to redo")
+			}
+			return ShowMessage(g, "Nothing to undo")
+		}
+		*to = append(*to, v.commit.SelectionSnapshot())
+		v.commit.RestoreSelection((*from)[len(*from)-1])
+		*from = (*from)[:len(*from)-1]
+
+		v.printContent()
+		return ShowMessage(g, message)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/awesome-gocui/gocui"
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	ir "github.com/smithjacobj/git-split/difftree"
+)
+
+// toggleVisual starts visual mode, anchoring a range at the cursor that extends to wherever the
+// cursor moves, or leaves it.
+func toggleVisual(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		if v.visualAnchor != nil {
+			v.visualAnchor = nil
+		} else {
+			_, y := v.View.Cursor()
+			v.visualAnchor = v.commit.LineMap[y]
+		}
+		v.printContent()
+		return nil
+	}
+}
+
+func exitVisual(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		if v.visualAnchor != nil {
+			v.visualAnchor = nil
+			v.printContent()
+		}
+		return nil
+	}
+}
+
+// visualRange returns the first and last line numbers of the range. If the anchor has been collapsed
+// into its chunk or file, the range starts there instead.
+func (v *MainView) visualRange() (first, last int) {
+	_, y := v.View.Cursor()
+	anchor := v.lineNumberOf(v.visualAnchor)
+	if chunk := chunkOf(v.visualAnchor); anchor < 0 && chunk != nil {
+		if anchor = v.lineNumberOf(chunk); anchor < 0 {
+			anchor = v.lineNumberOf(chunk.Parent)
+		}
+	}
+	if anchor < 0 {
+		anchor = y
+	}
+
+	if anchor < y {
+		return anchor, y
+	}
+	return y, anchor
+}
+
+// highlightVisual highlights the lines of the range, if in visual mode.
+func (v *MainView) highlightVisual() {
+	if v.visualAnchor == nil {
+		return
+	}
+	first, last := v.visualRange()
+	for y := first; y <= last; y++ {
+		v.View.SetHighlight(y, true)
+	}
+}
+
+// visualNodes returns what the range selects: its changed lines and line pairs, and the whole of any
+// collapsed chunks and files, or files without chunks. The lines of expanded chunks and files are only
+// selected where they are in the range.
+func (v *MainView) visualNodes() []ir.Selectable {
+	first, last := v.visualRange()
+	nodes := []ir.Selectable{}
+	for y := first; y <= last; y++ {
+		node := v.commit.LineMap[y]
+		if y > first && v.isContinuation(y) {
+			continue
+		}
+
+		switch node := node.(type) {
+		case *ir.File:
+			if node.Expanded == ir.Expanded && len(node.Chunks) > 0 {
+				continue
+			}
+		case *ir.Chunk:
+			if node.Expanded == ir.Expanded {
+				continue
+			}
+		case *ir.Line:
+			if node.Op == gitdiff.OpContext {
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// setVisualSelection sets the selection of the range, toggling it if toggle is true, and leaves
+// visual mode.
+func (v *MainView) setVisualSelection(state ir.SelectionState, toggle bool) {
+	nodes := v.visualNodes()
+	if toggle {
+		state = ir.RangeSelection(nodes)
+		state.Toggle()
+	}
+	v.changeSelection(func() {
+		ir.SetRangeSelection(nodes, state)
+	})
+	v.visualAnchor = nil
+	v.printContent()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/awesome-gocui/gocui"
+	"github.com/mattn/go-runewidth"
+)
+
+// toggleWrap switches soft wrapping of the lines of chunks in the unified layout, keeping the cursor
+// on the same node. Wrapped lines don't need scrolling horizontally.
+func toggleWrap(v *MainView) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		x, y := v.View.Cursor()
+		node := v.commit.LineMap[y]
+		v.wrap = !v.wrap
+		v.printContent()
+		v.View.SetCursor(x, v.lineNumberOf(node))
+		fixScroll(v.View)
+		if v.wrap && v.sideBySide {
+			return ShowMessage(g, "Lines are wrapped in the unified layout")
+		}
+		return nil
+	}
+}
+
+// scrollHorizontal scrolls the view left or right by half its width, but no further than needed to
+// see the end of the longest row.
+func scrollHorizontal(v *MainView, direction int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, _ *gocui.View) error {
+		width, _ := v.View.Size()
+		ox, oy := v.View.Origin()
+		v.View.SetOrigin(v.clampOriginX(ox+direction*width/2), oy)
+		return nil
+	}
+}
+
+// clampOriginX returns the horizontal origin ox, limited to what is needed to see the end of the
+// longest row. The side-by-side layout and wrapped lines already fit the view, so it is 0 for them.
+func (v *MainView) clampOriginX(ox int) int {
+	if v.sideBySide || v.wrap {
+		return 0
+	}
+	longest := 0
+	for _, line := range v.View.BufferLines() {
+		if w := runewidth.StringWidth(line); w > longest {
+			longest = w
+		}
+	}
+	width, _ := v.View.Size()
+	if maxOx := longest - width; ox > maxOx {
+		ox = maxOx
+	}
+	if ox < 0 {
+		ox = 0
+	}
+	return ox
+}
+
+// isContinuation returns whether the row at y continues the node of the row above it, like a
+// wrapped line or the virtual line for a missing end of line.
+func (v *MainView) isContinuation(y int) bool {
+	return y > 0 && y < len(v.commit.LineMap) && v.commit.LineMap[y] == v.commit.LineMap[y-1]
+}